@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/gagliardetto/solana-go"
+	"main/swap"
+)
+
+type balancesCmd struct {
+	JSON bool `arg:"--json" help:"print the portfolio as JSON"`
+}
+
+func doBalances(ctx context.Context, owner solana.PublicKey, args balancesCmd) {
+	portfolio, err := swap.GetPortfolio(ctx, clientRPC, owner)
+	if err != nil {
+		log.Fatalf("balances: %v", err)
+	}
+
+	if args.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(portfolio); err != nil {
+			log.Fatalf("balances: %v", err)
+		}
+		return
+	}
+
+	printPortfolio(portfolio)
+}
+
+func printPortfolio(portfolio *swap.Portfolio) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "SYMBOL\tAMOUNT\tSOL\tUSDC\tMINT\n")
+	for _, h := range portfolio.Holdings {
+		if !h.Priced {
+			fmt.Fprintf(w, "%v\t%v\t-\t-\t%v\n", h.Symbol, h.UIAmount, h.Mint)
+			continue
+		}
+		fmt.Fprintf(w, "%v\t%v\t%.6f\t%.2f\t%v\n", h.Symbol, h.UIAmount, h.ValueSOL, h.ValueUSDC, h.Mint)
+	}
+	fmt.Fprintf(w, "TOTAL\t\t%.6f\t%.2f\t\n", portfolio.TotalSOL, portfolio.TotalUSDC)
+	w.Flush()
+}
+
+// walletOwner public key of the signer, or --owner for watch only wallets
+func walletOwner(args cliArgs) solana.PublicKey {
+	if signer != nil {
+		return signer.PublicKey()
+	}
+	if args.Owner != "" {
+		return mustPublicKey(args.Owner)
+	}
+	log.Fatalf("wallet required, use --keypair, --unlock, --wallet or --owner")
+	return solana.PublicKey{}
+}
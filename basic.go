@@ -1,16 +1,19 @@
 package main
 
 import (
+	"context"
 	_ "embed"
+	"errors"
+	"github.com/gagliardetto/solana-go"
 	"log"
 	"main/swap"
 )
 
-func doSwap(args cliArgs) {
+func doSwap(ctx context.Context, args cliArgs) {
 	fromToken := args.FromToken
 	toToken := args.ToToken
 
-	pool, reverse := swap.GetPool(clientRPC, fromToken, toToken)
+	pool, reverse := swap.GetPool(ctx, clientRPC, fromToken, toToken)
 
 	if reverse == true {
 		if pool.BaseMint != toToken && pool.QuoteMint != fromToken {
@@ -22,30 +25,98 @@ func doSwap(args cliArgs) {
 		}
 	}
 
+	lookupTables := []solana.PublicKey{}
+	for _, t := range args.LookupTables {
+		table, err := solana.PublicKeyFromBase58(t)
+		if err != nil {
+			log.Fatalf("lookup table %v: %v", t, err)
+		}
+		lookupTables = append(lookupTables, table)
+	}
+
+	nonceAccount := solana.PublicKey{}
+	if args.NonceAccount != "" {
+		nonceAccount = mustPublicKey(args.NonceAccount)
+	}
+
+	owner := solana.PublicKey{}
+	if args.Owner != "" {
+		owner = mustPublicKey(args.Owner)
+	}
+
 	swapper, err := swap.NewTokenSwapper(swap.TokenSwapperConfig{
-		ClientRPC:  clientRPC,
-		PrivateKey: walletPK,
-		Pool:       &pool,
-		Reverse:    reverse,
+		ClientRPC: clientRPC,
+		Signer:    signer,
+		Owner:     owner,
+		FeePayer:  newFeePayer(args),
+		Pool:      &pool,
+		Reverse:   reverse,
+		PriorityFee: swap.PriorityFeeConfig{
+			UnitPrice:  args.CUPrice,
+			UnitLimit:  args.CULimit,
+			Auto:       args.CUPriceAuto,
+			Percentile: args.CUPercentile,
+		},
+		Execute: swap.ExecuteConfig{
+			Simulate:          args.Simulate,
+			ComputeUnitMargin: args.CUMargin,
+			LookupTables:      lookupTables,
+			WSEndpoint:        wsURL,
+			Commitment:        commitment,
+			NonceAccount:      nonceAccount,
+		},
+		Wrap: swap.WrapConfig{
+			UseATA:      args.WSOLATA,
+			KeepWrapped: args.KeepWSOL,
+		},
 	})
 
 	if err != nil {
 		log.Fatalf("create swapper: %v", err)
 	}
 
-	err = swapper.Init()
+	err = swapper.Init(ctx)
 
 	if err != nil {
 		log.Fatalf("init swapper %v", err)
 	}
 
-	sig, err := swapper.Do(
+	if args.Export != "" {
+		exportSwap(ctx, args, swapper, pool.ID)
+		return
+	}
+
+	res, err := swapper.Do(
+		ctx,
 		args.Amount,
 		args.Slipage,
 	)
 
+	if errors.Is(err, context.Canceled) {
+		if res != nil && !res.Signature.IsZero() {
+			log.Fatalf("interrupted, check whether %v landed before retrying", res.Signature)
+		}
+		log.Fatalf("interrupted before sending")
+	}
+	if errors.Is(err, swap.ErrBlockhashExpired) || errors.Is(err, swap.ErrNonceAdvanced) {
+		log.Fatalf("swap did not land, safe to retry: %v", err)
+	}
+	if errors.Is(err, swap.ErrExceededSlippage) {
+		log.Fatalf("slippage exceeded, retry with a lower --slipage: %v", err)
+	}
 	if err != nil {
 		log.Fatalf("swapper do %v", err)
 	}
-	log.Printf("sig: %v", sig)
+	log.Printf("sig: %v slot: %v confirmed in: %v", res.Signature, res.Slot, res.Duration)
+	if res.Filled {
+		log.Printf(
+			"in: %v out: %v quoted: %v min: %v slippage: %.4f%% fee: %v",
+			res.ActualIn,
+			res.ActualOut,
+			res.QuotedOut,
+			res.MinOut,
+			res.Slippage(),
+			res.Fee,
+		)
+	}
 }
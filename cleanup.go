@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/gagliardetto/solana-go"
+	"main/swap"
+)
+
+type cleanupCmd struct {
+	SwapDust bool    `arg:"--swap-dust" help:"swap small balances to SOL before closing accounts"`
+	MaxDust  float64 `arg:"--max-dust" default:"0.01" help:"largest balance, valued in SOL, swapped by --swap-dust"`
+	Slipage  float64 `arg:"--dust-slipage" default:"90" help:"slipage used by --swap-dust"`
+}
+
+func doCleanup(ctx context.Context, cliArgs cliArgs, args cleanupCmd) {
+	walletSigner := mustSigner()
+	owner := walletSigner.PublicKey()
+	cfg := swap.ExecuteConfig{WSEndpoint: wsURL, Commitment: commitment}
+
+	var feePayer swap.Signer
+	if args.SwapDust {
+		feePayer = newFeePayer(cliArgs)
+	}
+
+	accounts, err := swap.GetOwnerTokenAccounts(ctx, clientRPC, owner)
+	if err != nil {
+		log.Fatalf("get token accounts: %v", err)
+	}
+
+	if args.SwapDust {
+		swapped := false
+		for _, a := range accounts {
+			if a.Amount == 0 || a.Mint.Equals(solana.SolMint) || !a.Program.Equals(solana.TokenProgramID) {
+				continue
+			}
+			if swapDust(ctx, a, args.MaxDust, args.Slipage, feePayer, cfg) {
+				swapped = true
+			}
+		}
+		if swapped {
+			accounts, err = swap.GetOwnerTokenAccounts(ctx, clientRPC, owner)
+			if err != nil {
+				log.Fatalf("get token accounts: %v", err)
+			}
+		}
+	}
+
+	confs, reclaimed, unclosed, err := swap.CloseEmptyTokenAccounts(ctx, clientRPC, []swap.Signer{walletSigner}, accounts, cfg)
+	for _, conf := range confs {
+		log.Printf("sig: %v", conf.Signature)
+	}
+	log.Printf("reclaimed: %v SOL in %v transactions", swap.ToFloat(reclaimed, 9), len(confs))
+	for _, a := range unclosed {
+		log.Printf("not closed: %v mint: %v", a.Address, a.Mint)
+	}
+	if err != nil {
+		log.Fatalf("close token accounts: %v", err)
+	}
+	if len(unclosed) > 0 {
+		log.Fatalf("close token accounts: %v accounts not closed", len(unclosed))
+	}
+}
+
+// swapDust swaps the whole balance of a to SOL when it is worth at most maxDust SOL
+func swapDust(ctx context.Context, a swap.TokenAccount, maxDust float64, slipage float64, feePayer swap.Signer, cfg swap.ExecuteConfig) bool {
+	fromToken := a.Mint.String()
+	toToken := solana.SolMint.String()
+
+	pool, reverse := swap.GetPool(ctx, clientRPC, fromToken, toToken)
+	if pool.BaseMint != fromToken && pool.QuoteMint != fromToken {
+		log.Printf("dust %v: pool not found", a.Mint)
+		return false
+	}
+
+	decimals := pool.BaseDecimals
+	if reverse {
+		decimals = pool.QuoteDecimals
+	}
+	amount := swap.ToFloat(a.Amount, decimals)
+
+	swapper, err := swap.NewTokenSwapper(swap.TokenSwapperConfig{
+		ClientRPC: clientRPC,
+		Signer:    signer,
+		FeePayer:  feePayer,
+		Pool:      &pool,
+		Reverse:   reverse,
+		Execute:   cfg,
+	})
+	if err != nil {
+		log.Printf("dust %v: %v", a.Mint, err)
+		return false
+	}
+	if err := swapper.Init(ctx); err != nil {
+		log.Printf("dust %v: %v", a.Mint, err)
+		return false
+	}
+
+	estimated, _, _, _, _, err := swapper.EstimateRaw(ctx, a.Amount, slipage)
+	if err != nil {
+		log.Printf("dust %v: %v", a.Mint, err)
+		return false
+	}
+	if estimated > maxDust {
+		return false
+	}
+
+	res, err := swapper.DoRaw(ctx, a.Amount, slipage)
+	if err != nil {
+		log.Printf("dust %v: %v", a.Mint, err)
+		return false
+	}
+	log.Printf("dust %v: swapped %v for %v SOL sig: %v", a.Mint, amount, estimated, res.Signature)
+	return true
+}
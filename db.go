@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"main/models"
+)
+
+type dbCmd struct {
+	Migrate *dbMigrateCmd `arg:"subcommand:migrate" help:"apply pending schema migrations"`
+	Status  *dbStatusCmd  `arg:"subcommand:status" help:"list schema migrations and whether they are applied"`
+}
+
+type dbMigrateCmd struct {
+}
+
+type dbStatusCmd struct {
+	JSON bool `arg:"--json" help:"print the migrations as JSON"`
+}
+
+func doDB(args dbCmd) {
+	switch {
+	case args.Migrate != nil:
+		if err := models.Migrate(); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		version, err := models.SchemaVersion()
+		if err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		log.Printf("schema version: %v", version)
+	case args.Status != nil:
+		dbStatus(*args.Status)
+	default:
+		log.Fatalf("db: use migrate or status")
+	}
+}
+
+func dbStatus(args dbStatusCmd) {
+	states, err := models.MigrationStatus()
+	if err != nil {
+		log.Fatalf("status: %v", err)
+	}
+
+	if args.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(states); err != nil {
+			log.Fatalf("status: %v", err)
+		}
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "VERSION\tAPPLIED\tNAME\n")
+	for _, s := range states {
+		applied := "pending"
+		if s.AppliedAt != nil {
+			applied = s.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\n", s.Version, applied, s.Name)
+	}
+	w.Flush()
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/mr-tron/base58"
+	"main/swap"
+)
+
+type submitCmd struct {
+	Transaction          string `arg:"positional" help:"signed transaction, read from stdin when empty"`
+	Encoding             string `arg:"--encoding" default:"base64" help:"base64 or base58"`
+	LastValidBlockHeight uint64 `arg:"--last-valid-block-height" help:"last valid block height printed by --export"`
+}
+
+func exportSwap(ctx context.Context, args cliArgs, swapper *swap.TokenSwapper, pool string) {
+	tx, lastValidBlockHeight, quote, err := swapper.Build(ctx, args.Amount, args.Slipage)
+	if err != nil {
+		log.Fatalf("build: %v", err)
+	}
+
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		log.Fatalf("encode: %v", err)
+	}
+
+	var encoded string
+	switch args.Export {
+	case "base64":
+		encoded = base64.StdEncoding.EncodeToString(data)
+	case "base58":
+		encoded = base58.Encode(data)
+	default:
+		log.Fatalf("unknown encoding %q, expected base64 or base58", args.Export)
+	}
+
+	summary := []string{
+		fmt.Sprintf("pool:              %v", pool),
+		fmt.Sprintf("swap:              %v %v -> %v", args.Amount, args.FromToken, args.ToToken),
+		fmt.Sprintf("estimated out:     %v", quote.Estimated),
+		fmt.Sprintf("minimum out:       %v (%v)", quote.MinimumOut, quote.MinOut),
+		fmt.Sprintf("fee payer:         %v", tx.Message.AccountKeys[0]),
+		fmt.Sprintf("missing signers:   %v", swap.MissingSigners(tx)),
+	}
+	if nonceAccount, ok := swap.NonceAccountOf(tx); ok {
+		summary = append(summary, fmt.Sprintf("nonce account:     %v", nonceAccount))
+	} else {
+		summary = append(summary, fmt.Sprintf("last valid height: %v", lastValidBlockHeight))
+	}
+	for i, inst := range tx.Message.Instructions {
+		program, _ := tx.Message.Program(inst.ProgramIDIndex)
+		summary = append(summary, fmt.Sprintf("instruction %d:     %v", i, program))
+	}
+
+	fmt.Fprintln(os.Stderr, strings.Join(summary, "\n"))
+	fmt.Println(encoded)
+}
+
+func doSubmit(ctx context.Context, cliArgs cliArgs, args submitCmd) {
+	encoded := args.Transaction
+	if encoded == "" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			log.Fatalf("read transaction: %v", err)
+		}
+		encoded = string(data)
+	}
+	encoded = strings.TrimSpace(encoded)
+
+	var data []byte
+	var err error
+	switch args.Encoding {
+	case "base64":
+		data, err = base64.StdEncoding.DecodeString(encoded)
+	case "base58":
+		data, err = base58.Decode(encoded)
+	default:
+		log.Fatalf("unknown encoding %q, expected base64 or base58", args.Encoding)
+	}
+	if err != nil {
+		log.Fatalf("decode transaction: %v", err)
+	}
+
+	tx, err := solana.TransactionFromDecoder(bin.NewBinDecoder(data))
+	if err != nil {
+		log.Fatalf("decode transaction: %v", err)
+	}
+
+	// The fee payer signs here when the exported transaction still lacks its signature
+	feePayer := newFeePayer(cliArgs)
+	if feePayer != nil {
+		for _, key := range swap.MissingSigners(tx) {
+			if !key.Equals(feePayer.PublicKey()) {
+				continue
+			}
+			if err := swap.PartialSign(ctx, tx, []swap.Signer{feePayer}); err != nil {
+				log.Fatalf("fee payer sign: %v", err)
+			}
+		}
+	}
+
+	// Recorded as a trade of the wallet so an interrupted submit is recovered on the next start
+	conf, err := swap.SubmitTrade(ctx, clientRPC, submitOwner(cliArgs, tx, feePayer), tx, args.LastValidBlockHeight, swap.ExecuteConfig{
+		WSEndpoint: wsURL,
+		Commitment: commitment,
+	})
+	if err != nil {
+		log.Fatalf("submit: %v", err)
+	}
+	log.Printf("sig: %v slot: %v confirmed in: %v", conf.Signature, conf.Slot, conf.Duration)
+}
+
+// submitOwner wallet of a submitted swap: the signer, --owner, or the first signer
+// of tx other than the fee payer
+func submitOwner(args cliArgs, tx *solana.Transaction, feePayer swap.Signer) solana.PublicKey {
+	if signer != nil {
+		return signer.PublicKey()
+	}
+	if args.Owner != "" {
+		return mustPublicKey(args.Owner)
+	}
+	for _, key := range tx.Message.Signers() {
+		if feePayer == nil || !key.Equals(feePayer.PublicKey()) {
+			return key
+		}
+	}
+	return tx.Message.AccountKeys[0]
+}
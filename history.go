@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"main/models"
+)
+
+type historyCmd struct {
+	Limit  int           `arg:"--limit" default:"20" help:"number of trades, 0 for all"`
+	Status string        `arg:"--status" help:"only trades in this state: quoted, sent, confirmed, failed or expired"`
+	Pool   string        `arg:"--pool" help:"only trades of this pool id"`
+	Since  time.Duration `arg:"--since" help:"only trades of this last period, e.g. 24h"`
+	All    bool          `arg:"--all" help:"trades of every wallet instead of the current one"`
+	JSON   bool          `arg:"--json" help:"print the trades as JSON"`
+}
+
+func doHistory(args cliArgs, cmd historyCmd) {
+	filter := models.TradeFilter{
+		PoolID: cmd.Pool,
+		Status: cmd.Status,
+		Limit:  cmd.Limit,
+	}
+	if cmd.Since > 0 {
+		filter.Since = time.Now().Add(-cmd.Since)
+	}
+	if !cmd.All {
+		filter.Owner = walletOwner(args).String()
+	}
+
+	trades, err := models.GetTrades(filter)
+	if err != nil {
+		log.Fatalf("history: %v", err)
+	}
+
+	if cmd.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(trades); err != nil {
+			log.Fatalf("history: %v", err)
+		}
+		return
+	}
+
+	printTrades(trades)
+}
+
+func printTrades(trades []models.Trade) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "TIME\tSTATUS\tPOOL\tIN\tQUOTED\tMIN\tOUT\tSLIPPAGE\tFEE\tSIGNATURE\n")
+	for _, t := range trades {
+		out, slippage := "-", "-"
+		if t.Filled {
+			out = fmt.Sprint(t.ActualOut)
+			slippage = fmt.Sprintf("%.2f%%", t.Slippage)
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			t.CreatedAt.Format(time.RFC3339), t.Status, t.PoolID, t.Amount, t.QuotedOut, t.MinOut,
+			out, slippage, t.Fee, t.Signature)
+	}
+	w.Flush()
+}
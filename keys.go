@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"golang.org/x/term"
+	"main/keystore"
+)
+
+// passphraseEnv unlocks keystore wallets without a prompt
+const passphraseEnv = "KEYSTORE_PASSPHRASE"
+
+// stdin shared so consecutive prompts do not lose buffered input
+var stdin = bufio.NewReader(os.Stdin)
+
+type keystoreCmd struct {
+	Import   *keystoreImportCmd   `arg:"subcommand:import" help:"encrypt an existing key into the keystore"`
+	Generate *keystoreGenerateCmd `arg:"subcommand:generate" help:"generate a new wallet in the keystore"`
+	List     *keystoreListCmd     `arg:"subcommand:list" help:"list keystore wallets"`
+	Export   *keystoreExportCmd   `arg:"subcommand:export" help:"print the decrypted private key"`
+}
+
+type keystoreImportCmd struct {
+	Label string `arg:"positional,required" help:"wallet label"`
+	File  string `arg:"--file" help:"Solana CLI keypair file, the base58 key is prompted for otherwise"`
+}
+
+type keystoreGenerateCmd struct {
+	Label string `arg:"positional,required" help:"wallet label"`
+}
+
+type keystoreListCmd struct{}
+
+type keystoreExportCmd struct {
+	Label string `arg:"positional,required" help:"wallet label"`
+	JSON  bool   `arg:"--json" help:"print as a Solana CLI keypair byte array instead of base58"`
+}
+
+func doKeystore(dir string, args keystoreCmd) {
+	ks, err := keystore.Open(dir)
+	if err != nil {
+		log.Fatalf("open keystore: %v", err)
+	}
+
+	switch {
+	case args.Import != nil:
+		var key solana.PrivateKey
+		if args.Import.File != "" {
+			key, err = solana.PrivateKeyFromSolanaKeygenFile(args.Import.File)
+		} else {
+			var encoded string
+			encoded, err = readSecret("base58 private key: ")
+			if err == nil {
+				key, err = solana.PrivateKeyFromBase58(encoded)
+			}
+		}
+		if err != nil {
+			log.Fatalf("read key: %v", err)
+		}
+		entry, err := ks.Import(args.Import.Label, key, mustPassphrase(true))
+		if err != nil {
+			log.Fatalf("import: %v", err)
+		}
+		log.Printf("imported %v: %v", entry.Label, entry.PublicKey)
+	case args.Generate != nil:
+		entry, err := ks.Generate(args.Generate.Label, mustPassphrase(true))
+		if err != nil {
+			log.Fatalf("generate: %v", err)
+		}
+		log.Printf("generated %v: %v", entry.Label, entry.PublicKey)
+	case args.List != nil:
+		entries, err := ks.List()
+		if err != nil {
+			log.Fatalf("list: %v", err)
+		}
+		for _, e := range entries {
+			fmt.Printf("%v\t%v\n", e.Label, e.PublicKey)
+		}
+	case args.Export != nil:
+		key, err := ks.Unlock(args.Export.Label, mustPassphrase(false))
+		if err != nil {
+			log.Fatalf("unlock: %v", err)
+		}
+		if args.Export.JSON {
+			// encoding/json writes []byte as base64, the Solana CLI expects a number array
+			ints := make([]int, len(key))
+			for i, b := range key {
+				ints[i] = int(b)
+			}
+			data, err := json.Marshal(ints)
+			if err != nil {
+				log.Fatalf("export: %v", err)
+			}
+			fmt.Println(string(data))
+			return
+		}
+		fmt.Println(key.String())
+	default:
+		log.Fatalf("keystore: expected import, generate, list or export")
+	}
+}
+
+// unlockSigner returns the signer of the keystore wallet stored under label
+func unlockSigner(dir string, label string) *keystore.Signer {
+	ks, err := keystore.Open(dir)
+	if err != nil {
+		log.Fatalf("open keystore: %v", err)
+	}
+	if _, err := ks.Get(label); err != nil {
+		log.Fatalf("keystore: %v", err)
+	}
+	s, err := ks.Signer(label, mustPassphrase(false))
+	if err != nil {
+		log.Fatalf("unlock %v: %v", label, err)
+	}
+	return s
+}
+
+// mustPassphrase reads the passphrase from the environment or prompts for it,
+// confirm asks twice for new wallets. Both are trimmed so a wallet created with
+// one can be unlocked with the other.
+func mustPassphrase(confirm bool) string {
+	if passphrase := strings.TrimSpace(os.Getenv(passphraseEnv)); passphrase != "" {
+		return passphrase
+	}
+
+	passphrase, err := readSecret("passphrase: ")
+	if err != nil {
+		log.Fatalf("read passphrase: %v", err)
+	}
+	if confirm {
+		again, err := readSecret("repeat passphrase: ")
+		if err != nil {
+			log.Fatalf("read passphrase: %v", err)
+		}
+		if again != passphrase {
+			log.Fatalf("passphrases do not match")
+		}
+	}
+	return passphrase
+}
+
+// readSecret prompts on stderr and reads a line without echo when stdin is a terminal
+func readSecret(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		data, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return strings.TrimSpace(string(data)), err
+	}
+
+	line, err := stdin.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
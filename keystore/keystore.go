@@ -0,0 +1,264 @@
+package keystore
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	version = 1
+	kdfName = "argon2id"
+	// cipherName AEAD sealing the private key
+	cipherName = "xchacha20-poly1305"
+	saltSize   = 16
+	fileSuffix = ".json"
+)
+
+// DefaultKDFParams argon2id cost used for new wallets
+var DefaultKDFParams = KDFParams{
+	Time:    3,
+	Memory:  64 * 1024,
+	Threads: 4,
+}
+
+var (
+	// ErrWalletExists label is already used by another wallet
+	ErrWalletExists = errors.New("wallet already exists")
+	// ErrWalletNotFound no wallet with this label
+	ErrWalletNotFound = errors.New("wallet not found")
+	// ErrWrongPassphrase passphrase does not decrypt the wallet
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+	// ErrInvalidLabel label must be usable as a file name
+	ErrInvalidLabel = errors.New("invalid label, use letters, digits, '.', '_' or '-'")
+	// ErrInvalidKey x
+	ErrInvalidKey = errors.New("invalid private key")
+	// ErrEmptyPassphrase x
+	ErrEmptyPassphrase = errors.New("empty passphrase")
+)
+
+var labelPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// KDFParams argon2id cost parameters, Memory in KiB
+type KDFParams struct {
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+}
+
+// Entry public part of a stored wallet
+type Entry struct {
+	Label     string           `json:"label"`
+	PublicKey solana.PublicKey `json:"public_key"`
+}
+
+// file on disk layout of a wallet
+type file struct {
+	Version    int       `json:"version"`
+	Label      string    `json:"label"`
+	PublicKey  string    `json:"public_key"`
+	KDF        string    `json:"kdf"`
+	KDFParams  KDFParams `json:"kdf_params"`
+	Salt       []byte    `json:"salt"`
+	Cipher     string    `json:"cipher"`
+	Nonce      []byte    `json:"nonce"`
+	Ciphertext []byte    `json:"ciphertext"`
+}
+
+// Keystore directory of passphrase encrypted wallets, one file per label
+type Keystore struct {
+	dir    string
+	params KDFParams
+}
+
+// Open creates dir when missing and returns its keystore
+func Open(dir string) (*Keystore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &Keystore{dir: dir, params: DefaultKDFParams}, nil
+}
+
+// Import encrypts key under label
+func (k *Keystore) Import(label string, key solana.PrivateKey, passphrase string) (*Entry, error) {
+	if !labelPattern.MatchString(label) {
+		return nil, ErrInvalidLabel
+	}
+	if passphrase == "" {
+		return nil, ErrEmptyPassphrase
+	}
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, ErrInvalidKey
+	}
+
+	f := file{
+		Version:   version,
+		Label:     label,
+		PublicKey: key.PublicKey().String(),
+		KDF:       kdfName,
+		KDFParams: k.params,
+		Salt:      make([]byte, saltSize),
+		Cipher:    cipherName,
+		Nonce:     make([]byte, chacha20poly1305.NonceSizeX),
+	}
+	if _, err := rand.Read(f.Salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(f.Nonce); err != nil {
+		return nil, err
+	}
+
+	aead, err := chacha20poly1305.NewX(deriveKey(passphrase, f.Salt, f.KDFParams))
+	if err != nil {
+		return nil, err
+	}
+	f.Ciphertext = aead.Seal(nil, f.Nonce, key, f.additionalData())
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	// O_EXCL keeps an existing wallet from being overwritten
+	out, err := os.OpenFile(k.path(label), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("%w: %v", ErrWalletExists, label)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := out.Write(data); err != nil {
+		out.Close()
+		os.Remove(k.path(label))
+		return nil, err
+	}
+	if err := out.Close(); err != nil {
+		return nil, err
+	}
+
+	return &Entry{Label: label, PublicKey: key.PublicKey()}, nil
+}
+
+// Generate creates a new random wallet under label
+func (k *Keystore) Generate(label string, passphrase string) (*Entry, error) {
+	key, err := solana.NewRandomPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	return k.Import(label, key, passphrase)
+}
+
+// List stored wallets sorted by label
+func (k *Keystore) List() ([]Entry, error) {
+	paths, err := filepath.Glob(filepath.Join(k.dir, "*"+fileSuffix))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []Entry{}
+	for _, p := range paths {
+		f, err := readFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", p, err)
+		}
+		pub, err := solana.PublicKeyFromBase58(f.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", p, err)
+		}
+		entries = append(entries, Entry{Label: f.Label, PublicKey: pub})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Label < entries[j].Label
+	})
+	return entries, nil
+}
+
+// Get public part of the wallet stored under label
+func (k *Keystore) Get(label string) (*Entry, error) {
+	f, err := k.read(label)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := solana.PublicKeyFromBase58(f.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Entry{Label: f.Label, PublicKey: pub}, nil
+}
+
+// Unlock decrypts the private key stored under label
+func (k *Keystore) Unlock(label string, passphrase string) (solana.PrivateKey, error) {
+	f, err := k.read(label)
+	if err != nil {
+		return nil, err
+	}
+	if f.KDF != kdfName || f.Cipher != cipherName {
+		return nil, fmt.Errorf("unsupported kdf %v or cipher %v", f.KDF, f.Cipher)
+	}
+
+	aead, err := chacha20poly1305.NewX(deriveKey(passphrase, f.Salt, f.KDFParams))
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, f.Nonce, f.Ciphertext, f.additionalData())
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	key := solana.PrivateKey(plain)
+	if key.PublicKey().String() != f.PublicKey {
+		return nil, fmt.Errorf("wallet %v: decrypted key does not match public key", label)
+	}
+	return key, nil
+}
+
+func (k *Keystore) read(label string) (*file, error) {
+	if !labelPattern.MatchString(label) {
+		return nil, ErrInvalidLabel
+	}
+	f, err := readFile(k.path(label))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %v", ErrWalletNotFound, label)
+	}
+	return f, err
+}
+
+func (k *Keystore) path(label string) string {
+	return filepath.Join(k.dir, label+fileSuffix)
+}
+
+func readFile(path string) (*file, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if f.Version != version {
+		return nil, fmt.Errorf("unsupported keystore version %v", f.Version)
+	}
+	return &f, nil
+}
+
+// additionalData binds the ciphertext to its label and public key
+func (f *file) additionalData() []byte {
+	return []byte(strings.Join([]string{f.Label, f.PublicKey}, ":"))
+}
+
+func deriveKey(passphrase string, salt []byte, params KDFParams) []byte {
+	return argon2.IDKey([]byte(passphrase), salt, params.Time, params.Memory, params.Threads, chacha20poly1305.KeySize)
+}
@@ -0,0 +1,80 @@
+package keystore
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// openTest keystore in a temp dir with a cheap KDF cost
+func openTest(t *testing.T) *Keystore {
+	t.Helper()
+	k, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.params = KDFParams{Time: 1, Memory: 64, Threads: 1}
+	return k
+}
+
+func TestUnlock(t *testing.T) {
+	k := openTest(t)
+
+	key, err := solana.NewRandomPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.Import("main", key, "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		label      string
+		passphrase string
+		wantErr    error
+	}{
+		{name: "right passphrase", label: "main", passphrase: "secret"},
+		{name: "wrong passphrase", label: "main", passphrase: "other", wantErr: ErrWrongPassphrase},
+		{name: "unknown label", label: "nosuch", passphrase: "secret", wantErr: ErrWalletNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := k.Unlock(tt.label, tt.passphrase)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != key.String() {
+				t.Fatalf("unlocked key %v, want %v", got.PublicKey(), key.PublicKey())
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	k := openTest(t)
+
+	entry, err := k.Generate("new", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.Generate("new", "secret"); !errors.Is(err, ErrWalletExists) {
+		t.Fatalf("err = %v, want %v", err, ErrWalletExists)
+	}
+
+	key, err := k.Unlock("new", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.PublicKey().Equals(entry.PublicKey) {
+		t.Fatalf("unlocked key %v, want %v", key.PublicKey(), entry.PublicKey)
+	}
+}
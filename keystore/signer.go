@@ -0,0 +1,63 @@
+package keystore
+
+import (
+	"context"
+
+	"github.com/gagliardetto/solana-go"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Signer signs with a keystore wallet, the private key is decrypted for each signature
+// and cleared afterwards, only the derived encryption key stays in memory
+type Signer struct {
+	file      *file
+	key       []byte
+	publicKey solana.PublicKey
+}
+
+// Signer unlocks label once to check passphrase and returns its signer
+func (k *Keystore) Signer(label string, passphrase string) (*Signer, error) {
+	privateKey, err := k.Unlock(label, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	publicKey := privateKey.PublicKey()
+	zero(privateKey)
+
+	f, err := k.read(label)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Signer{
+		file:      f,
+		key:       deriveKey(passphrase, f.Salt, f.KDFParams),
+		publicKey: publicKey,
+	}, nil
+}
+
+// PublicKey x
+func (s *Signer) PublicKey() solana.PublicKey {
+	return s.publicKey
+}
+
+// SignMessage x
+func (s *Signer) SignMessage(ctx context.Context, message []byte) (solana.Signature, error) {
+	aead, err := chacha20poly1305.NewX(s.key)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	plain, err := aead.Open(nil, s.file.Nonce, s.file.Ciphertext, s.file.additionalData())
+	if err != nil {
+		return solana.Signature{}, ErrWrongPassphrase
+	}
+	defer zero(plain)
+
+	return solana.PrivateKey(plain).Sign(message)
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
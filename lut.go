@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/gagliardetto/solana-go"
+	"main/swap"
+)
+
+type createLUTCmd struct {
+	FromToken string `arg:"--from,required" help:"from"`
+	ToToken   string `arg:"--to,required" help:"to"`
+	Table     string `arg:"--table" help:"existing lookup table to extend"`
+}
+
+func createLookupTable(ctx context.Context, args createLUTCmd) {
+	walletSigner := mustSigner()
+
+	pool, _ := swap.GetPool(ctx, clientRPC, args.FromToken, args.ToToken)
+	if pool.ID == "" {
+		log.Fatalf("pool not found")
+	}
+
+	table := solana.PublicKey{}
+	if args.Table != "" {
+		table = mustPublicKey(args.Table)
+	} else if pool.LookupTable != "" {
+		table = solana.MustPublicKeyFromBase58(pool.LookupTable)
+	}
+
+	table, conf, err := swap.CreatePoolLookupTable(
+		ctx,
+		clientRPC,
+		[]swap.Signer{walletSigner},
+		&pool,
+		table,
+		swap.ExecuteConfig{WSEndpoint: wsURL, Commitment: commitment},
+	)
+	if err != nil {
+		log.Fatalf("create lookup table %v: %v", table, err)
+	}
+
+	err = pool.SetLookupTable(table.String())
+	if err != nil {
+		log.Fatalf("save lookup table: %v", err)
+	}
+
+	if conf == nil {
+		log.Printf("table: %v already holds the pool accounts", table)
+		return
+	}
+	log.Printf("table: %v sig: %v", table, conf.Signature)
+}
@@ -1,30 +1,216 @@
 package main
 
 import (
+	"context"
 	_ "embed"
 	"github.com/alexflint/go-arg"
 	"github.com/gagliardetto/solana-go/rpc"
+	"log"
 	"main/models"
+	"main/rpcpool"
+	"main/swap"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 )
 
 var rpcURL = ""
 
 var walletPK = ""
 
+var wsURL = ""
+
+// commitment every send waits for, set by --commitment
+var commitment = rpc.CommitmentConfirmed
+
 type cliArgs struct {
+	Keypair string `arg:"--keypair,env:SOLANA_KEYPAIR" help:"Solana CLI keypair file, the SOLANA_PRIVATE_KEY base58 key otherwise"`
+	RPC     string `arg:"--rpc,env:SOLANA_RPC_URL" help:"rpc url, used when no --endpoint is given"`
+	KeyDir  string `arg:"--keystore,env:KEYSTORE_DIR" default:"keystore" help:"encrypted keystore directory"`
+	Unlock  string `arg:"--unlock" help:"use the keystore wallet with this label, passphrase from KEYSTORE_PASSPHRASE or a prompt"`
+	Remote  string `arg:"--remote-signer,env:REMOTE_SIGNER_URL" help:"sign through a remote signing service, token from REMOTE_SIGNER_TOKEN"`
+	Wallet  string `arg:"--wallet,env:WALLET" help:"use the key of this registered wallet, see the wallet command"`
+
+	DB             string        `arg:"--db,env:DATABASE_URL" default:"orders.db" help:"sqlite file or postgres connection string, shared by bot instances"`
+	DBDriver       string        `arg:"--db-driver,env:DATABASE_DRIVER" help:"sqlite or postgres, derived from --db by default"`
+	DBMaxOpen      int           `arg:"--db-max-open,env:DATABASE_MAX_OPEN_CONNS" help:"maximum open database connections, unlimited by default"`
+	DBMaxIdle      int           `arg:"--db-max-idle,env:DATABASE_MAX_IDLE_CONNS" help:"maximum idle database connections"`
+	DBConnLifetime time.Duration `arg:"--db-conn-lifetime,env:DATABASE_CONN_MAX_LIFETIME" help:"close database connections after this age, e.g. 30m"`
+	DBConnIdleTime time.Duration `arg:"--db-conn-idle-time,env:DATABASE_CONN_MAX_IDLE_TIME" help:"close database connections idle for this long"`
+
+	FeePayer       string `arg:"--fee-payer,env:FEE_PAYER_KEYPAIR" help:"Solana CLI keypair file paying fees and rent of swaps instead of the wallet"`
+	FeePayerUnlock string `arg:"--fee-payer-unlock" help:"keystore wallet label paying fees and rent of swaps instead of the wallet"`
+
 	FromToken string  `arg:"--from" help:"from"`
 	ToToken   string  `arg:"--to" help:"to"`
 	Amount    float64 `arg:"--amount" help:"amount"`
 	Slipage   float64 `arg:"--slipage" help:"slipage"`
+
+	CUPrice      uint64   `arg:"--cu-price" help:"compute unit price in micro-lamports"`
+	CULimit      uint32   `arg:"--cu-limit" help:"compute unit limit"`
+	CUPriceAuto  bool     `arg:"--cu-price-auto" help:"derive compute unit price from recent prioritization fees"`
+	CUPercentile float64  `arg:"--cu-percentile" default:"75" help:"percentile of recent fees used by --cu-price-auto"`
+	Simulate     bool     `arg:"--simulate" help:"simulate before sending and size compute unit limit"`
+	CUMargin     float64  `arg:"--cu-margin" default:"10" help:"percent added to simulated compute units"`
+	LookupTables []string `arg:"--lookup-table,separate" help:"address lookup table used to build the transaction"`
+	WS           string   `arg:"--ws" help:"rpc websocket endpoint for confirmations, derived from the rpc url by default"`
+	NoWS         bool     `arg:"--no-ws" help:"confirm by polling only"`
+	Commitment   string   `arg:"--commitment" default:"confirmed" help:"processed, confirmed or finalized"`
+	Endpoints    []string `arg:"--endpoint,separate" help:"rpc endpoint as url[;weight=N][;mode=all|read|send], repeat for failover and broadcast"`
+	RateLimits   []string `arg:"--rate-limit,separate" help:"client side limit as method=rps, * for all other methods"`
+	NonceAccount string   `arg:"--nonce" help:"durable nonce account used instead of a recent blockhash"`
+	Owner        string   `arg:"--owner" help:"wallet public key, used with --export when no private key is configured"`
+	Export       string   `arg:"--export" help:"print the unsigned swap transaction as base64 or base58 instead of sending it"`
+	WSOLATA      bool     `arg:"--wsol-ata" help:"wrap SOL through the wallet wSOL token account instead of a temp account"`
+	KeepWSOL     bool     `arg:"--keep-wsol" help:"keep wSOL in the wallet token account after the swap, implies --wsol-ata"`
+
+	RecoverTimeout time.Duration `arg:"--recover-timeout" default:"3m" help:"how long to wait for swaps left pending by a previous run before refusing to swap"`
+
+	CreateLUT *createLUTCmd `arg:"subcommand:create-lut" help:"create or extend an address lookup table for a pool"`
+	Nonce     *nonceCmd     `arg:"subcommand:nonce" help:"manage durable nonce accounts"`
+	Submit    *submitCmd    `arg:"subcommand:submit" help:"send and confirm an externally signed transaction"`
+	Unwrap    *unwrapCmd    `arg:"subcommand:unwrap" help:"close the wallet wSOL token account and return SOL"`
+	Cleanup   *cleanupCmd   `arg:"subcommand:cleanup" help:"close empty token accounts and reclaim their rent"`
+	Keystore  *keystoreCmd  `arg:"subcommand:keystore" help:"manage encrypted wallets"`
+	Serve     *serveCmd     `arg:"subcommand:serve-signer" help:"serve the wallet over the remote signer protocol"`
+	Wallets   *walletCmd    `arg:"subcommand:wallet" help:"manage registered wallets"`
+	Balances  *balancesCmd  `arg:"subcommand:balances" help:"list wallet balances valued in SOL and USDC"`
+	History   *historyCmd   `arg:"subcommand:history" help:"list recorded swaps of the wallet"`
+	Database  *dbCmd        `arg:"subcommand:db" help:"manage the database schema"`
 }
 
 var clientRPC *rpc.Client
 
 func main() {
 	var args cliArgs
-	arg.MustParse(&args)
-	models.Init()
-	clientRPC = rpc.New(rpcURL)
+	p := arg.MustParse(&args)
+	parsed, err := swap.ParseCommitment(args.Commitment)
+	if err != nil {
+		p.Fail(err.Error())
+	}
+	commitment = parsed
+
+	err = models.Init(models.Config{
+		Driver:          args.DBDriver,
+		DSN:             args.DB,
+		MaxOpenConns:    args.DBMaxOpen,
+		MaxIdleConns:    args.DBMaxIdle,
+		ConnMaxLifetime: args.DBConnLifetime,
+		ConnMaxIdleTime: args.DBConnIdleTime,
+		// db status reports pending migrations before they are applied
+		SkipMigrate: args.Database != nil,
+	})
+	if err != nil {
+		log.Fatalf("database: %v", err)
+	}
+	defer models.Close()
+
+	// db commands only need the database, not a wallet or rpc
+	if args.Database != nil {
+		doDB(*args.Database)
+		return
+	}
+
+	if args.RPC != "" {
+		rpcURL = args.RPC
+	}
+	if args.Wallet != "" {
+		applyWallet(&args)
+	}
+	key, err := loadWallet(args.Keypair)
+	if err != nil {
+		log.Fatalf("wallet key: %v", err)
+	}
+	wallet = key
+
+	clientRPC = newClientRPC(args.Endpoints, args.RateLimits)
+
+	switch {
+	case args.NoWS:
+		wsURL = ""
+	case args.WS != "":
+		wsURL = args.WS
+	case wsURL == "":
+		wsURL = strings.Replace(rpcURL, "http", "ws", 1)
+	}
+
+	// Interrupt cancels in-flight rpc calls and confirmation waits
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	signer = newSigner(ctx, args)
+	if args.Wallet != "" {
+		checkWallet(args.Wallet)
+	}
+
+	// Swaps left pending by a crash are resolved before anything can swap again
+	if swaps(args) {
+		mustRecoverTrades(ctx, walletOwner(args), args.RecoverTimeout)
+	}
+
+	switch {
+	case args.CreateLUT != nil:
+		createLookupTable(ctx, *args.CreateLUT)
+	case args.Nonce != nil:
+		doNonce(ctx, *args.Nonce)
+	case args.Submit != nil:
+		doSubmit(ctx, args, *args.Submit)
+	case args.Unwrap != nil:
+		doUnwrap(ctx, *args.Unwrap)
+	case args.Cleanup != nil:
+		doCleanup(ctx, args, *args.Cleanup)
+	case args.Keystore != nil:
+		doKeystore(args.KeyDir, *args.Keystore)
+	case args.Serve != nil:
+		doServe(ctx, *args.Serve)
+	case args.Wallets != nil:
+		doWallet(ctx, args.KeyDir, *args.Wallets)
+	case args.Balances != nil:
+		doBalances(ctx, walletOwner(args), *args.Balances)
+	case args.History != nil:
+		doHistory(args, *args.History)
+	default:
+		doSwap(ctx, args)
+	}
+}
+
+// swaps reports whether the command may send a swap
+func swaps(args cliArgs) bool {
+	switch {
+	case args.Cleanup != nil:
+		return args.Cleanup.SwapDust
+	case args.CreateLUT != nil, args.Nonce != nil, args.Submit != nil, args.Unwrap != nil,
+		args.Keystore != nil, args.Serve != nil, args.Wallets != nil, args.Balances != nil,
+		args.History != nil:
+		return false
+	}
+	return args.Export == ""
+}
+
+func newClientRPC(endpoints []string, rateLimits []string) *rpc.Client {
+	parsed := []rpcpool.Endpoint{}
+	for _, e := range endpoints {
+		endpoint, err := rpcpool.ParseEndpoint(e)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		parsed = append(parsed, endpoint)
+	}
+	if len(parsed) == 0 {
+		parsed = append(parsed, rpcpool.Endpoint{URL: rpcURL})
+	}
+	if rpcURL == "" {
+		rpcURL = parsed[0].URL
+	}
+
+	limits := rpcpool.Limits{}
+	for _, l := range rateLimits {
+		if err := rpcpool.ParseLimit(limits, l); err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
 
-	doSwap(args)
+	return rpc.NewWithCustomRPCClient(rpcpool.NewLimiter(rpcpool.New(parsed...), limits))
 }
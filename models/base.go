@@ -1,30 +1,126 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+// Database drivers
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// DefaultDSN sqlite file in the working directory
+const DefaultDSN = "orders.db"
+
+// ErrUnknownDriver x
+var ErrUnknownDriver = errors.New("unknown database driver, use sqlite or postgres")
+
 var db *gorm.DB
 
+// Config database connection
+type Config struct {
+	// Driver sqlite or postgres, derived from DSN when empty
+	Driver string
+	// DSN sqlite file path or postgres connection string, DefaultDSN when empty
+	DSN string
+	// MaxOpenConns zero means unlimited
+	MaxOpenConns int
+	// MaxIdleConns zero keeps the database/sql default
+	MaxIdleConns int
+	// ConnMaxLifetime zero means connections are reused forever
+	ConnMaxLifetime time.Duration
+	// ConnMaxIdleTime zero means idle connections are not closed for their age
+	ConnMaxIdleTime time.Duration
+	// SkipMigrate leaves pending migrations for an explicit Migrate
+	SkipMigrate bool
+}
+
 // Init models
-func Init() {
+func Init(cfg Config) error {
 
-	conn, err := gorm.Open(sqlite.Open("orders.db"), &gorm.Config{
+	dialector, err := open(cfg)
+	if err != nil {
+		return err
+	}
+
+	conn, err := gorm.Open(dialector, &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Warn),
 	})
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
 
+	sqlDB, err := conn.DB()
 	if err != nil {
-		panic("failed to connect database")
+		return err
+	}
+	if cfg.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 	}
 
 	db = conn
 
-	err = db.AutoMigrate(&PoolConfig{})
+	if cfg.SkipMigrate {
+		return nil
+	}
+	if err := Migrate(); err != nil {
+		return fmt.Errorf("migrate database: %w", err)
+	}
+
+	return nil
+}
+
+// Close the database connections
+func Close() error {
+	if db == nil {
+		return nil
+	}
+	sqlDB, err := db.DB()
 	if err != nil {
-		panic(err)
+		return err
+	}
+	return sqlDB.Close()
+}
+
+func open(cfg Config) (gorm.Dialector, error) {
+	dsn := cfg.DSN
+	if dsn == "" {
+		dsn = DefaultDSN
+	}
+
+	driver := cfg.Driver
+	if driver == "" {
+		driver = DriverSQLite
+		if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") || strings.Contains(dsn, "host=") {
+			driver = DriverPostgres
+		}
+	}
+
+	switch driver {
+	case DriverSQLite:
+		return sqlite.Open(dsn), nil
+	case DriverPostgres:
+		return postgres.Open(dsn), nil
 	}
+	return nil, fmt.Errorf("%w: %v", ErrUnknownDriver, driver)
 }
 
 // GetDB for other methods
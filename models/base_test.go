@@ -0,0 +1,58 @@
+package models
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenDriver(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        Config
+		wantDriver string
+		wantErr    error
+	}{
+		{name: "default", cfg: Config{}, wantDriver: DriverSQLite},
+		{name: "sqlite file", cfg: Config{DSN: "/var/lib/bot/orders.db"}, wantDriver: DriverSQLite},
+		{name: "postgres url", cfg: Config{DSN: "postgres://bot@db/orders"}, wantDriver: DriverPostgres},
+		{name: "postgresql url", cfg: Config{DSN: "postgresql://bot@db/orders"}, wantDriver: DriverPostgres},
+		{name: "postgres keywords", cfg: Config{DSN: "host=db user=bot dbname=orders"}, wantDriver: DriverPostgres},
+		{name: "explicit driver", cfg: Config{Driver: DriverSQLite, DSN: "postgres.db"}, wantDriver: DriverSQLite},
+		{name: "unknown driver", cfg: Config{Driver: "mysql"}, wantErr: ErrUnknownDriver},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dialector, err := open(tt.cfg)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dialector.Name() != tt.wantDriver {
+				t.Fatalf("driver %v, want %v", dialector.Name(), tt.wantDriver)
+			}
+		})
+	}
+}
+
+func TestInitPool(t *testing.T) {
+	err := Init(Config{DSN: filepath.Join(t.TempDir(), "test.db"), MaxOpenConns: 3, MaxIdleConns: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close()
+
+	sqlDB, err := GetDB().DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if max := sqlDB.Stats().MaxOpenConnections; max != 3 {
+		t.Fatalf("max open connections %v, want 3", max)
+	}
+}
@@ -0,0 +1,264 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrSchemaTooNew database was migrated by a newer version
+var ErrSchemaTooNew = errors.New("database schema is newer than this version, upgrade before using it")
+
+// migrationLock postgres advisory lock key serializing migrations of instances sharing a database
+const migrationLock = 8150221
+
+// Migration one numbered schema change, applied once in a transaction
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+}
+
+// SchemaMigration applied migration
+type SchemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+// MigrationState migration with the time it was applied, nil when pending
+type MigrationState struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"appliedAt"`
+}
+
+// Migrations in version order, append only. Tables created by AutoMigrate before
+// versioning match the first ones, so each step checks what already exists.
+var Migrations = []Migration{
+	{1, "create pool_configs", func(tx *gorm.DB) error {
+		return createTable(tx, &poolConfigV1{})
+	}},
+	{2, "add pool_configs.lookup_table", func(tx *gorm.DB) error {
+		return addColumn(tx, "pool_configs", "lookup_table", "text")
+	}},
+	{3, "create wallets", func(tx *gorm.DB) error {
+		return createTable(tx, &walletV3{})
+	}},
+	{4, "create trades", func(tx *gorm.DB) error {
+		return createTable(tx, &tradeV4{})
+	}},
+	{5, "add trades blockhash, last_valid_block_height and nonce_account", func(tx *gorm.DB) error {
+		if err := addColumn(tx, "trades", "blockhash", "text"); err != nil {
+			return err
+		}
+		if err := addColumn(tx, "trades", "last_valid_block_height", "bigint"); err != nil {
+			return err
+		}
+		return addColumn(tx, "trades", "nonce_account", "text")
+	}},
+}
+
+// Migrate applies pending migrations in order
+func Migrate() error {
+
+	var applied map[int]SchemaMigration
+	err := migrationTx(func(tx *gorm.DB) error {
+		var err error
+		applied, err = appliedMigrations(tx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := checkSchemaVersion(applied); err != nil {
+		return err
+	}
+
+	for _, m := range Migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		err := migrationTx(func(tx *gorm.DB) error {
+			// Another instance may have applied it since applied was read
+			var count int64
+			if err := tx.Model(&SchemaMigration{}).Where("version = ?", m.Version).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return nil
+			}
+
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			log.Printf("migrated %v: %v", m.Version, m.Name)
+			return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %v %v: %w", m.Version, m.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// migrationTx runs fn in a transaction holding the migration lock on postgres,
+// with the version table created under the same lock
+func migrationTx(fn func(tx *gorm.DB) error) error {
+	return GetDB().Transaction(func(tx *gorm.DB) error {
+		if tx.Dialector.Name() == DriverPostgres {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLock).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.AutoMigrate(&SchemaMigration{}); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// SchemaVersion latest applied migration, zero for an unversioned database
+func SchemaVersion() (int, error) {
+
+	if !GetDB().Migrator().HasTable(&SchemaMigration{}) {
+		return 0, nil
+	}
+
+	var version int
+	err := GetDB().Model(&SchemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+// MigrationStatus known migrations and those applied by newer versions
+func MigrationStatus() ([]MigrationState, error) {
+
+	applied := map[int]SchemaMigration{}
+	if GetDB().Migrator().HasTable(&SchemaMigration{}) {
+		var err error
+		if applied, err = appliedMigrations(GetDB()); err != nil {
+			return nil, err
+		}
+	}
+
+	states := []MigrationState{}
+	known := map[int]bool{}
+	for _, m := range Migrations {
+		known[m.Version] = true
+		state := MigrationState{Version: m.Version, Name: m.Name}
+		if a, ok := applied[m.Version]; ok {
+			state.AppliedAt = &a.AppliedAt
+		}
+		states = append(states, state)
+	}
+	for _, a := range applied {
+		if !known[a.Version] {
+			a := a
+			states = append(states, MigrationState{Version: a.Version, Name: a.Name, AppliedAt: &a.AppliedAt})
+		}
+	}
+
+	return states, nil
+}
+
+func appliedMigrations(tx *gorm.DB) (map[int]SchemaMigration, error) {
+
+	var rows []SchemaMigration
+	if err := tx.Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	applied := map[int]SchemaMigration{}
+	for _, r := range rows {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+func checkSchemaVersion(applied map[int]SchemaMigration) error {
+	latest := Migrations[len(Migrations)-1].Version
+	for version := range applied {
+		if version > latest {
+			return fmt.Errorf("%w: version %v, known %v", ErrSchemaTooNew, version, latest)
+		}
+	}
+	return nil
+}
+
+func createTable(tx *gorm.DB, model interface{}) error {
+	if tx.Migrator().HasTable(model) {
+		return nil
+	}
+	return tx.Migrator().CreateTable(model)
+}
+
+func addColumn(tx *gorm.DB, table string, column string, columnType string) error {
+	if tx.Migrator().HasColumn(table, column) {
+		return nil
+	}
+	return tx.Exec(fmt.Sprintf("ALTER TABLE %v ADD COLUMN %v %v", tx.Statement.Quote(table), tx.Statement.Quote(column), columnType)).Error
+}
+
+// Tables as first created, later migrations change them so the models can not be used
+
+type poolConfigV1 struct {
+	BaseModel
+	ID               string
+	BaseMint         string
+	QuoteMint        string
+	BaseDecimals     int
+	QuoteDecimals    int
+	OpenOrders       string
+	TargetOrders     string
+	BaseVault        string
+	QuoteVault       string
+	MarketID         string
+	MarketBaseVault  string
+	MarketQuoteVault string
+	MarketBids       string
+	MarketAsks       string
+	MarketEventQueue string
+}
+
+func (poolConfigV1) TableName() string { return "pool_configs" }
+
+type walletV3 struct {
+	BaseModel
+	Label     string `gorm:"uniqueIndex"`
+	PublicKey string
+	KeyRef    string
+	Tags      string
+}
+
+func (walletV3) TableName() string { return "wallets" }
+
+type tradeV4 struct {
+	BaseModel
+	Owner     string `gorm:"index"`
+	PoolID    string
+	Reverse   bool
+	InMint    string
+	OutMint   string
+	Amount    uint64
+	QuotedOut uint64
+	MinOut    uint64
+	Slipage   float64
+	Slippage  float64
+	Signature string `gorm:"index"`
+	Status    string `gorm:"index"`
+	Error     string
+	ActualIn  uint64
+	ActualOut uint64
+	Filled    bool
+	Fee       uint64
+	Slot      uint64
+	SentAt    *time.Time
+	LandedAt  *time.Time
+}
+
+func (tradeV4) TableName() string { return "trades" }
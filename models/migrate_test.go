@@ -0,0 +1,103 @@
+package models
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// initTest opens a sqlite database in a temp dir
+func initTest(t *testing.T, skipMigrate bool) {
+	t.Helper()
+	if err := Init(Config{DSN: filepath.Join(t.TempDir(), "test.db"), SkipMigrate: skipMigrate}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Close() })
+}
+
+func TestMigrate(t *testing.T) {
+	latest := Migrations[len(Migrations)-1].Version
+
+	tests := []struct {
+		name string
+		// baseline creates the schema before Migrate, nil for a fresh database
+		baseline func(t *testing.T)
+	}{
+		{name: "fresh database"},
+		{name: "baseline schema", baseline: func(t *testing.T) {
+			// Tables were created by AutoMigrate of the first models before versioning
+			if err := GetDB().AutoMigrate(&poolConfigV1{}); err != nil {
+				t.Fatal(err)
+			}
+			if err := GetDB().Create(&poolConfigV1{ID: "pool", BaseMint: "base", QuoteMint: "quote"}).Error; err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			initTest(t, true)
+			if tt.baseline != nil {
+				tt.baseline(t)
+			}
+
+			if err := Migrate(); err != nil {
+				t.Fatal(err)
+			}
+			// Applied migrations are skipped
+			if err := Migrate(); err != nil {
+				t.Fatal(err)
+			}
+
+			version, err := SchemaVersion()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if version != latest {
+				t.Fatalf("schema version = %v, want %v", version, latest)
+			}
+
+			for _, model := range []interface{}{&PoolConfig{}, &Wallet{}, &Trade{}} {
+				if !GetDB().Migrator().HasTable(model) {
+					t.Fatalf("missing table for %T", model)
+				}
+			}
+			for table, columns := range map[string][]string{
+				"pool_configs": {"lookup_table"},
+				"trades":       {"blockhash", "last_valid_block_height", "nonce_account"},
+			} {
+				for _, column := range columns {
+					if !GetDB().Migrator().HasColumn(table, column) {
+						t.Fatalf("missing column %v.%v", table, column)
+					}
+				}
+			}
+
+			if tt.baseline != nil {
+				pool := GetPoolConfig("base", "quote")
+				if pool.ID != "pool" {
+					t.Fatalf("pool config lost, got %+v", pool)
+				}
+				if err := pool.SetLookupTable("table"); err != nil {
+					t.Fatal(err)
+				}
+				if got := GetPoolConfig("base", "quote").LookupTable; got != "table" {
+					t.Fatalf("lookup table = %q, want %q", got, "table")
+				}
+			}
+		})
+	}
+}
+
+func TestMigrateSchemaTooNew(t *testing.T) {
+	initTest(t, false)
+
+	latest := Migrations[len(Migrations)-1].Version
+	if err := GetDB().Create(&SchemaMigration{Version: latest + 1, Name: "from a newer version"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := Migrate(); !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("err = %v, want %v", err, ErrSchemaTooNew)
+	}
+}
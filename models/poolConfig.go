@@ -18,6 +18,7 @@ type PoolConfig struct {
 	MarketBids       string `json:"marketBids"`
 	MarketAsks       string `json:"marketAsks"`
 	MarketEventQueue string `json:"marketEventQueue"`
+	LookupTable      string `json:"lookupTable"`
 }
 
 // Create poolConfig
@@ -30,6 +31,17 @@ func (poolConfig *PoolConfig) Create() error {
 	return nil
 }
 
+// SetLookupTable stores the address lookup table of the pool
+func (poolConfig *PoolConfig) SetLookupTable(table string) error {
+
+	if dbc := GetDB().Model(&PoolConfig{}).Where("id = ?", poolConfig.ID).Update("lookup_table", table); dbc.Error != nil {
+		return dbc.Error
+	}
+	poolConfig.LookupTable = table
+
+	return nil
+}
+
 // GetPoolConfig new
 func GetPoolConfig(fromToken string, toToken string) PoolConfig {
 
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"time"
+)
+
+// Trade states in lifecycle order, a trade ends confirmed, failed or expired
+const (
+	// TradeQuoted swap was estimated, nothing sent yet
+	TradeQuoted = "quoted"
+	// TradeSent transaction was signed and about to be broadcast, confirmation pending
+	TradeSent = "sent"
+	// TradeConfirmed transaction landed without error
+	TradeConfirmed = "confirmed"
+	// TradeFailed transaction landed with an error or could not be sent
+	TradeFailed = "failed"
+	// TradeExpired blockhash or nonce expired before the transaction landed
+	TradeExpired = "expired"
+)
+
+// Trade t
+type Trade struct {
+	BaseModel
+	Owner   string `gorm:"index" json:"owner"`
+	PoolID  string `json:"poolId"`
+	Reverse bool   `json:"reverse"`
+	InMint  string `json:"inMint"`
+	OutMint string `json:"outMint"`
+	// Amount requested input in base units
+	Amount    uint64 `json:"amount"`
+	QuotedOut uint64 `json:"quotedOut"`
+	MinOut    uint64 `json:"minOut"`
+	// Slipage requested percent of the quote accepted as minimum out
+	Slipage float64 `json:"slipage"`
+	// Slippage realized percent below the quote, set with the fill
+	Slippage  float64 `json:"slippage"`
+	Signature string  `gorm:"index" json:"signature"`
+	// Blockhash, LastValidBlockHeight and NonceAccount tell when a sent trade can no longer land
+	Blockhash            string     `json:"blockhash"`
+	LastValidBlockHeight uint64     `json:"lastValidBlockHeight"`
+	NonceAccount         string     `json:"nonceAccount"`
+	Status               string     `gorm:"index" json:"status"`
+	Error                string     `json:"error"`
+	ActualIn             uint64     `json:"actualIn"`
+	ActualOut            uint64     `json:"actualOut"`
+	Filled               bool       `json:"filled"`
+	Fee                  uint64     `json:"fee"`
+	Slot                 uint64     `json:"slot"`
+	SentAt               *time.Time `json:"sentAt"`
+	LandedAt             *time.Time `json:"landedAt"`
+}
+
+// TradeFilter selects trades, zero fields match everything
+type TradeFilter struct {
+	Owner  string
+	PoolID string
+	Status string
+	Since  time.Time
+	Limit  int
+}
+
+// Create trade
+func (trade *Trade) Create() error {
+
+	if dbc := GetDB().Create(trade); dbc.Error != nil {
+		return dbc.Error
+	}
+
+	return nil
+}
+
+// Save all fields of the trade
+func (trade *Trade) Save() error {
+
+	if dbc := GetDB().Save(trade); dbc.Error != nil {
+		return dbc.Error
+	}
+
+	return nil
+}
+
+// GetPendingTrades trades of owner not yet confirmed, failed or expired, oldest first
+func GetPendingTrades(owner string) ([]Trade, error) {
+
+	var trades []Trade
+	err := GetDB().Where("owner = ? AND status IN ?", owner, []string{TradeQuoted, TradeSent}).Order("created_at").Find(&trades).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return trades, nil
+}
+
+// GetTrades newest first
+func GetTrades(filter TradeFilter) ([]Trade, error) {
+
+	query := GetDB().Model(&Trade{}).Order("created_at desc")
+	if filter.Owner != "" {
+		query = query.Where("owner = ?", filter.Owner)
+	}
+	if filter.PoolID != "" {
+		query = query.Where("pool_id = ?", filter.PoolID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var trades []Trade
+	if err := query.Find(&trades).Error; err != nil {
+		return nil, err
+	}
+
+	return trades, nil
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetTrades(t *testing.T) {
+	initTest(t, false)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	trades := []Trade{
+		{Owner: "a", PoolID: "p1", Status: TradeConfirmed},
+		{Owner: "a", PoolID: "p2", Status: TradeFailed},
+		{Owner: "b", PoolID: "p1", Status: TradeConfirmed},
+		{Owner: "a", PoolID: "p1", Status: TradeSent},
+		{Owner: "a", PoolID: "p1", Status: TradeQuoted},
+	}
+	for i := range trades {
+		trades[i].CreatedAt = start.Add(time.Duration(i) * time.Hour)
+		if err := trades[i].Create(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// want indexes into trades, newest first
+	tests := []struct {
+		name   string
+		filter TradeFilter
+		want   []int
+	}{
+		{name: "all", want: []int{4, 3, 2, 1, 0}},
+		{name: "owner", filter: TradeFilter{Owner: "a"}, want: []int{4, 3, 1, 0}},
+		{name: "pool", filter: TradeFilter{PoolID: "p1"}, want: []int{4, 3, 2, 0}},
+		{name: "status", filter: TradeFilter{Status: TradeConfirmed}, want: []int{2, 0}},
+		{name: "since", filter: TradeFilter{Since: start.Add(2 * time.Hour)}, want: []int{4, 3, 2}},
+		{name: "limit", filter: TradeFilter{Owner: "a", Limit: 2}, want: []int{4, 3}},
+		{name: "combined", filter: TradeFilter{Owner: "a", PoolID: "p1", Status: TradeConfirmed}, want: []int{0}},
+		{name: "no match", filter: TradeFilter{Owner: "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetTrades(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("%v trades, want %v", len(got), len(tt.want))
+			}
+			for i, idx := range tt.want {
+				if got[i].ID != trades[idx].ID {
+					t.Fatalf("trade %v is %v, want %v", i, got[i].ID, trades[idx].ID)
+				}
+			}
+		})
+	}
+
+	pending, err := GetPendingTrades("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Oldest first so recovery resolves trades in the order they were sent
+	if len(pending) != 2 || pending[0].ID != trades[3].ID || pending[1].ID != trades[4].ID {
+		t.Fatalf("pending trades %+v, want the sent then the quoted trade", pending)
+	}
+}
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrWalletNotFound x
+var ErrWalletNotFound = errors.New("wallet not found")
+
+// ErrInvalidKeyRef x
+var ErrInvalidKeyRef = errors.New("invalid key reference")
+
+// Kinds of key references
+const (
+	KeyRefKeystore = "keystore"
+	KeyRefKeypair  = "keypair"
+	KeyRefRemote   = "remote"
+	KeyRefWatch    = "watch"
+)
+
+// Wallet w
+type Wallet struct {
+	BaseModel
+	Label     string `gorm:"uniqueIndex" json:"label"`
+	PublicKey string `json:"publicKey"`
+	// KeyRef where the key lives: keystore:<label>, keypair:<path>, remote:<url> or watch
+	KeyRef string `json:"keyRef"`
+	// Tags comma separated
+	Tags string `json:"tags"`
+}
+
+// Create wallet
+func (wallet *Wallet) Create() error {
+
+	if dbc := GetDB().Create(wallet); dbc.Error != nil {
+		return dbc.Error
+	}
+
+	return nil
+}
+
+// NewKeyRef joins kind and value into a key reference, watch has no value
+func NewKeyRef(kind string, value string) string {
+	if kind == KeyRefWatch {
+		return kind
+	}
+	return kind + ":" + value
+}
+
+// ParseKeyRef splits the key reference of the wallet into its kind and value
+func (wallet *Wallet) ParseKeyRef() (string, string, error) {
+	if wallet.KeyRef == KeyRefWatch {
+		return KeyRefWatch, "", nil
+	}
+
+	// Paths and urls may contain more colons, only the first one ends the kind
+	kind, value, ok := strings.Cut(wallet.KeyRef, ":")
+	if ok && value != "" {
+		switch kind {
+		case KeyRefKeystore, KeyRefKeypair, KeyRefRemote:
+			return kind, value, nil
+		}
+	}
+	return "", "", fmt.Errorf("%w %q", ErrInvalidKeyRef, wallet.KeyRef)
+}
+
+// TagList tags of the wallet
+func (wallet *Wallet) TagList() []string {
+	tags := []string{}
+	for _, t := range strings.Split(wallet.Tags, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// HasTag x
+func (wallet *Wallet) HasTag(tag string) bool {
+	for _, t := range wallet.TagList() {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// GetWallet by label
+func GetWallet(label string) (Wallet, error) {
+
+	var wallet Wallet
+	err := GetDB().Where("label = ?", label).First(&wallet).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return wallet, ErrWalletNotFound
+	}
+
+	return wallet, err
+}
+
+// GetWallets all wallets, only those with tag when it is set
+func GetWallets(tag string) ([]Wallet, error) {
+
+	var wallets []Wallet
+	if err := GetDB().Order("label").Find(&wallets).Error; err != nil {
+		return nil, err
+	}
+	if tag == "" {
+		return wallets, nil
+	}
+
+	tagged := []Wallet{}
+	for _, w := range wallets {
+		if w.HasTag(tag) {
+			tagged = append(tagged, w)
+		}
+	}
+	return tagged, nil
+}
+
+// DeleteWallet by label
+func DeleteWallet(label string) error {
+
+	dbc := GetDB().Unscoped().Where("label = ?", label).Delete(&Wallet{})
+	if dbc.Error != nil {
+		return dbc.Error
+	}
+	if dbc.RowsAffected == 0 {
+		return ErrWalletNotFound
+	}
+
+	return nil
+}
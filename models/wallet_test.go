@@ -0,0 +1,48 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseKeyRef(t *testing.T) {
+	tests := []struct {
+		keyRef    string
+		wantKind  string
+		wantValue string
+		wantErr   bool
+	}{
+		{keyRef: "keystore:main", wantKind: KeyRefKeystore, wantValue: "main"},
+		{keyRef: "keypair:/keys/id.json", wantKind: KeyRefKeypair, wantValue: "/keys/id.json"},
+		{keyRef: "keypair:C:/keys/id.json", wantKind: KeyRefKeypair, wantValue: "C:/keys/id.json"},
+		{keyRef: "remote:https://signer.example:8443", wantKind: KeyRefRemote, wantValue: "https://signer.example:8443"},
+		{keyRef: "watch", wantKind: KeyRefWatch},
+		{keyRef: "", wantErr: true},
+		{keyRef: "keystore", wantErr: true},
+		{keyRef: "keystore:", wantErr: true},
+		{keyRef: "watch:x", wantErr: true},
+		{keyRef: "ledger:0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		w := Wallet{KeyRef: tt.keyRef}
+		kind, value, err := w.ParseKeyRef()
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidKeyRef) {
+				t.Errorf("ParseKeyRef(%q) = %q %q %v, want %v", tt.keyRef, kind, value, err, ErrInvalidKeyRef)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseKeyRef(%q): %v", tt.keyRef, err)
+			continue
+		}
+		if kind != tt.wantKind || value != tt.wantValue {
+			t.Errorf("ParseKeyRef(%q) = %q %q, want %q %q", tt.keyRef, kind, value, tt.wantKind, tt.wantValue)
+		}
+		// Stored references are built by NewKeyRef
+		if ref := NewKeyRef(kind, value); ref != tt.keyRef {
+			t.Errorf("NewKeyRef(%q, %q) = %q, want %q", kind, value, ref, tt.keyRef)
+		}
+	}
+}
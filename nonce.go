@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/gagliardetto/solana-go"
+	"main/swap"
+)
+
+type nonceCmd struct {
+	Create   *nonceCreateCmd   `arg:"subcommand:create" help:"create a nonce account"`
+	Show     *nonceShowCmd     `arg:"subcommand:show" help:"show the stored nonce"`
+	Advance  *nonceAdvanceCmd  `arg:"subcommand:advance" help:"store a new nonce, transactions using the current one can no longer land"`
+	Withdraw *nonceWithdrawCmd `arg:"subcommand:withdraw" help:"withdraw lamports, the whole balance closes the account"`
+}
+
+type nonceCreateCmd struct {
+	Authority string `arg:"--authority" help:"nonce authority, the wallet by default"`
+}
+
+type nonceShowCmd struct {
+	Account string `arg:"positional,required" help:"nonce account"`
+}
+
+type nonceAdvanceCmd struct {
+	Account string `arg:"positional,required" help:"nonce account"`
+}
+
+type nonceWithdrawCmd struct {
+	Account   string `arg:"positional,required" help:"nonce account"`
+	Recipient string `arg:"--recipient" help:"recipient, the wallet by default"`
+	Lamports  uint64 `arg:"--lamports" help:"lamports to withdraw, the whole balance by default"`
+}
+
+func doNonce(ctx context.Context, args nonceCmd) {
+	walletSigner := mustSigner()
+	signers := []swap.Signer{walletSigner}
+	cfg := swap.ExecuteConfig{WSEndpoint: wsURL, Commitment: commitment}
+
+	switch {
+	case args.Create != nil:
+		authority := walletSigner.PublicKey()
+		if args.Create.Authority != "" {
+			authority = mustPublicKey(args.Create.Authority)
+		}
+		account, conf, err := swap.CreateNonceAccount(ctx, clientRPC, signers, authority, cfg)
+		if err != nil {
+			log.Fatalf("create nonce account %v: %v", account, err)
+		}
+		log.Printf("nonce account: %v sig: %v", account, conf.Signature)
+	case args.Show != nil:
+		nonce, err := swap.GetNonceAccount(ctx, clientRPC, mustPublicKey(args.Show.Account))
+		if err != nil {
+			log.Fatalf("get nonce account: %v", err)
+		}
+		log.Printf("nonce: %v authority: %v", solana.Hash(nonce.Nonce), nonce.AuthorizedPubkey)
+	case args.Advance != nil:
+		conf, err := swap.AdvanceNonceAccount(ctx, clientRPC, signers, mustPublicKey(args.Advance.Account), cfg)
+		if err != nil {
+			log.Fatalf("advance nonce account: %v", err)
+		}
+		log.Printf("sig: %v", conf.Signature)
+	case args.Withdraw != nil:
+		recipient := walletSigner.PublicKey()
+		if args.Withdraw.Recipient != "" {
+			recipient = mustPublicKey(args.Withdraw.Recipient)
+		}
+		conf, err := swap.WithdrawNonceAccount(
+			ctx,
+			clientRPC,
+			signers,
+			mustPublicKey(args.Withdraw.Account),
+			recipient,
+			args.Withdraw.Lamports,
+			cfg,
+		)
+		if err != nil {
+			log.Fatalf("withdraw nonce account: %v", err)
+		}
+		log.Printf("sig: %v", conf.Signature)
+	default:
+		log.Fatalf("nonce: expected create, show, advance or withdraw")
+	}
+}
+
+func mustPublicKey(s string) solana.PublicKey {
+	key, err := solana.PublicKeyFromBase58(s)
+	if err != nil {
+		log.Fatalf("%v: %v", s, err)
+	}
+	return key
+}
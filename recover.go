@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"main/swap"
+)
+
+// mustRecoverTrades resolves swaps left pending by a previous run and refuses to go on
+// while any of them may still land, so a restart never repeats a swap
+func mustRecoverTrades(ctx context.Context, owner solana.PublicKey, timeout time.Duration) {
+	recoverCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	pending, err := swap.RecoverTrades(recoverCtx, clientRPC, owner, 0)
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		log.Fatalf("recover trades: %v", err)
+	}
+	for _, t := range pending {
+		if t.NonceAccount != "" {
+			log.Printf("trade %v: %v may still land until nonce %v is advanced, run nonce advance %v", t.ID, t.Signature, t.NonceAccount, t.NonceAccount)
+			continue
+		}
+		log.Printf("trade %v: %v still pending", t.ID, t.Signature)
+	}
+	if len(pending) > 0 {
+		log.Fatalf("%v pending trades unresolved, retry later", len(pending))
+	}
+}
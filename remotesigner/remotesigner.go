@@ -0,0 +1,250 @@
+// Package remotesigner signs transaction messages through an HTTP signing service.
+//
+// Protocol, JSON bodies, optional "Authorization: Bearer <token>" header:
+//
+//	GET  /v1/public-key  -> {"public_key": "<base58>"}
+//	POST /v1/sign        {"public_key": "<base58>", "message": "<base64>"}
+//	                     -> {"signature": "<base58>"}
+//
+// Errors use a non 2xx status with {"error": "<text>"}.
+package remotesigner
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+const (
+	publicKeyPath = "/v1/public-key"
+	signPath      = "/v1/sign"
+	// maxMessageSize transaction size limit with some margin for the JSON envelope
+	maxMessageSize = 64 * 1024
+)
+
+var (
+	// ErrUnauthorized token missing or wrong
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrUnknownKey signer does not hold the requested key
+	ErrUnknownKey = errors.New("unknown public key")
+)
+
+// Signer key held by the server
+type Signer interface {
+	PublicKey() solana.PublicKey
+	SignMessage(ctx context.Context, message []byte) (solana.Signature, error)
+}
+
+type publicKeyResponse struct {
+	PublicKey string `json:"public_key"`
+}
+
+type signRequest struct {
+	PublicKey string `json:"public_key"`
+	Message   string `json:"message"`
+}
+
+type signResponse struct {
+	Signature string `json:"signature"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Client signs through a remote signing service
+type Client struct {
+	url        string
+	token      string
+	publicKey  solana.PublicKey
+	httpClient *http.Client
+}
+
+// New returns a client for the service at url, the public key is fetched when zero
+func New(ctx context.Context, url string, token string, publicKey solana.PublicKey) (*Client, error) {
+	c := &Client{
+		url:        strings.TrimRight(url, "/"),
+		token:      token,
+		publicKey:  publicKey,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+
+	if publicKey.IsZero() {
+		var res publicKeyResponse
+		if err := c.do(ctx, http.MethodGet, publicKeyPath, nil, &res); err != nil {
+			return nil, err
+		}
+		key, err := solana.PublicKeyFromBase58(res.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("remote signer public key: %w", err)
+		}
+		c.publicKey = key
+	}
+
+	return c, nil
+}
+
+// PublicKey x
+func (c *Client) PublicKey() solana.PublicKey {
+	return c.publicKey
+}
+
+// SignMessage x
+func (c *Client) SignMessage(ctx context.Context, message []byte) (solana.Signature, error) {
+	req := signRequest{
+		PublicKey: c.publicKey.String(),
+		Message:   base64.StdEncoding.EncodeToString(message),
+	}
+
+	var res signResponse
+	if err := c.do(ctx, http.MethodPost, signPath, req, &res); err != nil {
+		return solana.Signature{}, err
+	}
+
+	sig, err := solana.SignatureFromBase58(res.Signature)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("remote signer signature: %w", err)
+	}
+	if !sig.Verify(c.publicKey, message) {
+		return solana.Signature{}, errors.New("remote signer returned an invalid signature")
+	}
+	return sig, nil
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		var e errorResponse
+		json.NewDecoder(res.Body).Decode(&e)
+		if res.StatusCode == http.StatusUnauthorized {
+			return fmt.Errorf("remote signer: %w", ErrUnauthorized)
+		}
+		if e.Error == ErrUnknownKey.Error() {
+			return fmt.Errorf("remote signer: %w", ErrUnknownKey)
+		}
+		return fmt.Errorf("remote signer: %v: %v", res.Status, e.Error)
+	}
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// Handler serves the protocol for signers, used as a local stand-in for the signing service.
+// An empty token disables authentication.
+type Handler struct {
+	token   string
+	signers map[solana.PublicKey]Signer
+	first   solana.PublicKey
+}
+
+// NewHandler x
+func NewHandler(token string, signers ...Signer) *Handler {
+	h := &Handler{
+		token:   token,
+		signers: map[solana.PublicKey]Signer{},
+	}
+	for i, s := range signers {
+		if i == 0 {
+			h.first = s.PublicKey()
+		}
+		h.signers[s.PublicKey()] = s
+	}
+	return h
+}
+
+// ServeHTTP x
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := []byte(r.Header.Get("Authorization"))
+	if h.token != "" && subtle.ConstantTimeCompare(auth, []byte("Bearer "+h.token)) != 1 {
+		writeError(w, http.StatusUnauthorized, ErrUnauthorized)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == publicKeyPath:
+		writeJSON(w, http.StatusOK, publicKeyResponse{PublicKey: h.first.String()})
+	case r.Method == http.MethodPost && r.URL.Path == signPath:
+		h.sign(w, r)
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+func (h *Handler) sign(w http.ResponseWriter, r *http.Request) {
+	var req signRequest
+	err := json.NewDecoder(io.LimitReader(r.Body, maxMessageSize)).Decode(&req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	key, err := solana.PublicKeyFromBase58(req.PublicKey)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	signer, ok := h.signers[key]
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrUnknownKey)
+		return
+	}
+
+	message, err := base64.StdEncoding.DecodeString(req.Message)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	sig, err := signer.SignMessage(r.Context(), message)
+	if err != nil {
+		log.Printf("remote signer: sign with %v: %v", key, err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	log.Printf("remote signer: signed %v bytes with %v", len(message), key)
+
+	writeJSON(w, http.StatusOK, signResponse{Signature: sig.String()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
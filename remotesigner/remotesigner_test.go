@@ -0,0 +1,94 @@
+package remotesigner
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+type keySigner struct {
+	key solana.PrivateKey
+	// signWith signs with another key to produce signatures that do not verify
+	signWith solana.PrivateKey
+}
+
+func (s keySigner) PublicKey() solana.PublicKey {
+	return s.key.PublicKey()
+}
+
+func (s keySigner) SignMessage(ctx context.Context, message []byte) (solana.Signature, error) {
+	if s.signWith != nil {
+		return s.signWith.Sign(message)
+	}
+	return s.key.Sign(message)
+}
+
+func newKey(t *testing.T) solana.PrivateKey {
+	t.Helper()
+	key, err := solana.NewRandomPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	key := newKey(t)
+	message := []byte("message")
+
+	server := httptest.NewServer(NewHandler("token", keySigner{key: key}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		url       string
+		token     string
+		publicKey solana.PublicKey
+		wantErr   error
+	}{
+		{name: "fetch key and sign", token: "token"},
+		{name: "wrong token", token: "other", wantErr: ErrUnauthorized},
+		{name: "unknown key", token: "token", publicKey: newKey(t).PublicKey(), wantErr: ErrUnknownKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := New(ctx, server.URL, tt.token, tt.publicKey)
+			if err == nil {
+				_, err = client.SignMessage(ctx, message)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !client.PublicKey().Equals(key.PublicKey()) {
+				t.Fatalf("public key = %v, want %v", client.PublicKey(), key.PublicKey())
+			}
+		})
+	}
+}
+
+func TestClientRejectsBadSignature(t *testing.T) {
+	ctx := context.Background()
+	key := newKey(t)
+
+	server := httptest.NewServer(NewHandler("token", keySigner{key: key, signWith: newKey(t)}))
+	defer server.Close()
+
+	client, err := New(ctx, server.URL, "token", solana.PublicKey{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.SignMessage(ctx, []byte("message")); err == nil {
+		t.Fatal("invalid signature accepted")
+	}
+}
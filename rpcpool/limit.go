@@ -0,0 +1,287 @@
+package rpcpool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"golang.org/x/time/rate"
+)
+
+var _ rpc.JSONRPCClient = &Limiter{}
+
+const (
+	// DefaultMaxRetries on 429 and 5xx responses
+	DefaultMaxRetries = 4
+	// retryBaseDelay doubled on every attempt
+	retryBaseDelay = 250 * time.Millisecond
+	// retryMaxDelay between two attempts
+	retryMaxDelay = 5 * time.Second
+	// coalesceWindow concurrent getAccountInfo calls are collected for
+	coalesceWindow = 5 * time.Millisecond
+	// maxMultipleAccounts per getMultipleAccounts call
+	maxMultipleAccounts = 100
+	// coalesceTimeout of a merged getMultipleAccounts call
+	coalesceTimeout = 20 * time.Second
+)
+
+// Limits requests per second by method, "*" applies to methods without their own limit
+type Limits map[string]float64
+
+// ParseLimit parses "method=rps" into limits
+func ParseLimit(limits Limits, s string) error {
+	kv := strings.SplitN(s, "=", 2)
+	if len(kv) != 2 {
+		return fmt.Errorf("rate limit %q: expected method=rps", s)
+	}
+	rps, err := strconv.ParseFloat(kv[1], 64)
+	if err != nil || rps <= 0 {
+		return fmt.Errorf("rate limit %q: invalid rps", s)
+	}
+	limits[kv[0]] = rps
+	return nil
+}
+
+// Limiter wraps a client with per-method token buckets, retries with backoff
+// on 429/5xx and coalescing of concurrent getAccountInfo calls
+type Limiter struct {
+	client     rpc.JSONRPCClient
+	limiters   map[string]*rate.Limiter
+	maxRetries int
+
+	mu      sync.Mutex
+	batches map[string]*accountBatch
+}
+
+// NewLimiter x
+func NewLimiter(client rpc.JSONRPCClient, limits Limits) *Limiter {
+	l := &Limiter{
+		client:     client,
+		limiters:   map[string]*rate.Limiter{},
+		maxRetries: DefaultMaxRetries,
+		batches:    map[string]*accountBatch{},
+	}
+	for method, rps := range limits {
+		burst := int(rps)
+		if burst < 1 {
+			burst = 1
+		}
+		l.limiters[method] = rate.NewLimiter(rate.Limit(rps), burst)
+	}
+	return l
+}
+
+func (l *Limiter) wait(ctx context.Context, method string) error {
+	limiter, ok := l.limiters[method]
+	if !ok {
+		limiter, ok = l.limiters["*"]
+	}
+	if !ok {
+		return nil
+	}
+	return limiter.Wait(ctx)
+}
+
+// isRetryable reports whether err is a rate limit or server side failure
+func isRetryable(err error) bool {
+	var httpErr *jsonrpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code == http.StatusTooManyRequests || httpErr.Code >= 500
+	}
+	var rpcErr *jsonrpc.RPCError
+	if errors.As(err, &rpcErr) {
+		return rpcErr.Code == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// retry calls fn with rate limiting until it succeeds, fails with a permanent error or retries run out
+func (l *Limiter) retry(ctx context.Context, method string, fn func() error) error {
+	delay := retryBaseDelay
+	for attempt := 0; ; attempt++ {
+		if err := l.wait(ctx, method); err != nil {
+			return err
+		}
+
+		err := fn()
+		if err == nil || !isRetryable(err) || attempt >= l.maxRetries {
+			return err
+		}
+
+		// Full jitter keeps concurrent callers from retrying in lockstep
+		sleep := time.Duration(rand.Int63n(int64(delay))) + delay/2
+		select {
+		case <-time.After(sleep):
+		case <-ctx.Done():
+			return err
+		}
+		if delay *= 2; delay > retryMaxDelay {
+			delay = retryMaxDelay
+		}
+	}
+}
+
+// CallForInto x
+func (l *Limiter) CallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	if method == "getAccountInfo" && len(params) > 0 {
+		return l.getAccountInfo(ctx, out, params)
+	}
+	return l.retry(ctx, method, func() error {
+		return l.client.CallForInto(ctx, out, method, params)
+	})
+}
+
+// CallWithCallback x
+func (l *Limiter) CallWithCallback(
+	ctx context.Context,
+	method string,
+	params []interface{},
+	callback func(*http.Request, *http.Response) error,
+) error {
+	return l.retry(ctx, method, func() error {
+		return l.client.CallWithCallback(ctx, method, params, callback)
+	})
+}
+
+// CallBatch x
+func (l *Limiter) CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	var res jsonrpc.RPCResponses
+	err := l.retry(ctx, "*", func() error {
+		var err error
+		res, err = l.client.CallBatch(ctx, requests)
+		return err
+	})
+	return res, err
+}
+
+type accountRead struct {
+	done  chan struct{}
+	value json.RawMessage
+	slot  json.RawMessage
+	err   error
+}
+
+type accountBatch struct {
+	config  interface{}
+	keys    []string
+	reads   map[string]*accountRead
+	flushed bool
+}
+
+// getAccountInfo joins concurrent reads with the same config into one getMultipleAccounts call.
+// The merged call counts against the getMultipleAccounts limit, each read also against its own
+// getAccountInfo limit when one is set.
+func (l *Limiter) getAccountInfo(ctx context.Context, out interface{}, params []interface{}) error {
+	if limiter, ok := l.limiters["getAccountInfo"]; ok {
+		if err := limiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
+
+	key := fmt.Sprint(params[0])
+
+	var config interface{}
+	if len(params) > 1 {
+		config = params[1]
+	}
+	configKey, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	batch, ok := l.batches[string(configKey)]
+	if !ok {
+		batch = &accountBatch{
+			config: config,
+			reads:  map[string]*accountRead{},
+		}
+		l.batches[string(configKey)] = batch
+		time.AfterFunc(coalesceWindow, func() { l.flush(string(configKey), batch) })
+	}
+	read, ok := batch.reads[key]
+	if !ok {
+		read = &accountRead{done: make(chan struct{})}
+		batch.reads[key] = read
+		batch.keys = append(batch.keys, key)
+	}
+	// A full batch takes no more keys, later reads start a new one
+	full := len(batch.keys) >= maxMultipleAccounts
+	if full && l.batches[string(configKey)] == batch {
+		delete(l.batches, string(configKey))
+	}
+	l.mu.Unlock()
+
+	if full {
+		go l.flush(string(configKey), batch)
+	}
+
+	select {
+	case <-read.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if read.err != nil {
+		return read.err
+	}
+
+	res, err := json.Marshal(map[string]json.RawMessage{
+		"context": read.slot,
+		"value":   read.value,
+	})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(res, out)
+}
+
+func (l *Limiter) flush(configKey string, batch *accountBatch) {
+	l.mu.Lock()
+	if batch.flushed {
+		l.mu.Unlock()
+		return
+	}
+	batch.flushed = true
+	if l.batches[configKey] == batch {
+		delete(l.batches, configKey)
+	}
+	l.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), coalesceTimeout)
+	defer cancel()
+
+	params := []interface{}{batch.keys}
+	if batch.config != nil {
+		params = append(params, batch.config)
+	}
+
+	var res struct {
+		Context json.RawMessage   `json:"context"`
+		Value   []json.RawMessage `json:"value"`
+	}
+	err := l.retry(ctx, "getMultipleAccounts", func() error {
+		return l.client.CallForInto(ctx, &res, "getMultipleAccounts", params)
+	})
+	if err == nil && len(res.Value) != len(batch.keys) {
+		err = fmt.Errorf("getMultipleAccounts: expected %d accounts, got %d", len(batch.keys), len(res.Value))
+	}
+
+	for i, key := range batch.keys {
+		read := batch.reads[key]
+		read.err = err
+		if err == nil {
+			read.slot = res.Context
+			read.value = res.Value[i]
+		}
+		close(read.done)
+	}
+}
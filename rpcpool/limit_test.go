@@ -0,0 +1,252 @@
+package rpcpool
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		method  string
+		rps     float64
+		wantErr bool
+	}{
+		{in: "getAccountInfo=10", method: "getAccountInfo", rps: 10},
+		{in: "*=2.5", method: "*", rps: 2.5},
+		{in: "sendTransaction=0.5", method: "sendTransaction", rps: 0.5},
+		{in: "getAccountInfo", wantErr: true},
+		{in: "getAccountInfo=", wantErr: true},
+		{in: "getAccountInfo=x", wantErr: true},
+		{in: "getAccountInfo=0", wantErr: true},
+		{in: "getAccountInfo=-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		limits := Limits{}
+		err := ParseLimit(limits, tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseLimit(%q) = %v, want error", tt.in, limits)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseLimit(%q): %v", tt.in, err)
+			continue
+		}
+		if len(limits) != 1 || limits[tt.method] != tt.rps {
+			t.Errorf("ParseLimit(%q) = %v, want %v=%v", tt.in, limits, tt.method, tt.rps)
+		}
+	}
+}
+
+// testServer answers json rpc requests with handle, a status other than 200 is sent without a body
+func testServer(t *testing.T, handle func(method string, params []json.RawMessage) (interface{}, int)) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     interface{}       `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+			return
+		}
+		result, status := handle(req.Method, req.Params)
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+			"result":  result,
+		})
+	}))
+}
+
+// accountServer answers getMultipleAccounts with accounts holding the lamports registered
+// for their key and records the keys of every call
+func accountServer(t *testing.T, lamports map[string]uint64) (*httptest.Server, func() [][]string) {
+	var mu sync.Mutex
+	calls := [][]string{}
+
+	server := testServer(t, func(method string, params []json.RawMessage) (interface{}, int) {
+		if method != "getMultipleAccounts" {
+			t.Errorf("unexpected request %v", method)
+			return nil, http.StatusBadRequest
+		}
+		var keys []string
+		if err := json.Unmarshal(params[0], &keys); err != nil {
+			t.Errorf("decode keys: %v", err)
+			return nil, http.StatusBadRequest
+		}
+		mu.Lock()
+		calls = append(calls, keys)
+		mu.Unlock()
+
+		accounts := []interface{}{}
+		for _, key := range keys {
+			accounts = append(accounts, map[string]interface{}{
+				"data":       []string{"", "base64"},
+				"executable": false,
+				"lamports":   lamports[key],
+				"owner":      solana.SystemProgramID.String(),
+				"rentEpoch":  0,
+			})
+		}
+		return map[string]interface{}{
+			"context": map[string]interface{}{"slot": 1},
+			"value":   accounts,
+		}, http.StatusOK
+	})
+
+	return server, func() [][]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+}
+
+func TestCoalesceAccountReads(t *testing.T) {
+	tests := []struct {
+		name  string
+		reads int
+		// minCalls when the reads do not fit one getMultipleAccounts call
+		minCalls int
+	}{
+		{name: "one batch", reads: 10, minCalls: 1},
+		{name: "split full batches", reads: 2*maxMultipleAccounts + 10, minCalls: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lamports := map[string]uint64{}
+			keys := []solana.PublicKey{}
+			for i := 0; i < tt.reads; i++ {
+				key := solana.NewWallet().PublicKey()
+				keys = append(keys, key)
+				lamports[key.String()] = uint64(i + 1)
+			}
+
+			server, calls := accountServer(t, lamports)
+			defer server.Close()
+			client := rpc.NewWithCustomRPCClient(NewLimiter(jsonrpc.NewClient(server.URL), Limits{}))
+
+			start := make(chan struct{})
+			var wg sync.WaitGroup
+			for _, key := range keys {
+				wg.Add(1)
+				go func(key solana.PublicKey) {
+					defer wg.Done()
+					<-start
+					res, err := client.GetAccountInfo(context.Background(), key)
+					if err != nil {
+						t.Errorf("get %v: %v", key, err)
+						return
+					}
+					if res.Value.Lamports != lamports[key.String()] {
+						t.Errorf("get %v: lamports %v, want %v", key, res.Value.Lamports, lamports[key.String()])
+					}
+				}(key)
+			}
+			close(start)
+			wg.Wait()
+
+			got := calls()
+			if len(got) < tt.minCalls || len(got) >= tt.reads {
+				t.Fatalf("%v reads made %v getMultipleAccounts calls", tt.reads, len(got))
+			}
+			total := 0
+			for _, c := range got {
+				if len(c) > maxMultipleAccounts {
+					t.Errorf("call with %v keys", len(c))
+				}
+				total += len(c)
+			}
+			if total != tt.reads {
+				t.Errorf("%v keys requested, want %v", total, tt.reads)
+			}
+		})
+	}
+}
+
+func TestCoalescedReadsRespectLimit(t *testing.T) {
+	key := solana.NewWallet().PublicKey()
+	server, _ := accountServer(t, map[string]uint64{key.String(): 1})
+	defer server.Close()
+
+	// Two reads per second with a burst of two, the third waits for a token
+	client := rpc.NewWithCustomRPCClient(NewLimiter(jsonrpc.NewClient(server.URL), Limits{"getAccountInfo": 2}))
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := client.GetAccountInfo(context.Background(), key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Fatalf("3 reads at 2 rps took %v", elapsed)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  []int
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "success", wantCalls: 1},
+		{name: "rate limited", failures: []int{http.StatusTooManyRequests}, wantCalls: 2},
+		{name: "server error", failures: []int{http.StatusServiceUnavailable}, wantCalls: 2},
+		{name: "bad request", failures: []int{http.StatusBadRequest}, wantCalls: 1, wantErr: true},
+		{name: "retries run out", failures: []int{http.StatusTooManyRequests, http.StatusBadGateway}, wantCalls: 2, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			calls := 0
+			server := testServer(t, func(method string, params []json.RawMessage) (interface{}, int) {
+				mu.Lock()
+				defer mu.Unlock()
+				calls++
+				if calls <= len(tt.failures) {
+					return nil, tt.failures[calls-1]
+				}
+				return map[string]interface{}{"context": map[string]interface{}{"slot": 1}, "value": 5}, http.StatusOK
+			})
+			defer server.Close()
+
+			limiter := NewLimiter(jsonrpc.NewClient(server.URL), Limits{})
+			limiter.maxRetries = 1
+			client := rpc.NewWithCustomRPCClient(limiter)
+
+			res, err := client.GetBalance(context.Background(), solana.NewWallet().PublicKey(), rpc.CommitmentConfirmed)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("want error")
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if res.Value != 5 {
+				t.Fatalf("balance = %v, want 5", res.Value)
+			}
+			if calls != tt.wantCalls {
+				t.Fatalf("%v calls, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,332 @@
+package rpcpool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+var _ rpc.JSONRPCClient = &Pool{}
+
+// ErrNoEndpoints x
+var ErrNoEndpoints = errors.New("no rpc endpoints available")
+
+const (
+	// minCooldown after the first failure of an endpoint
+	minCooldown = time.Second
+	// maxCooldown between retries of a failing endpoint
+	maxCooldown = time.Minute
+	// broadcastTimeout for a single endpoint to accept a transaction
+	broadcastTimeout = 20 * time.Second
+)
+
+// Mode of an endpoint
+type Mode string
+
+const (
+	// ModeAll endpoint serves reads and sends
+	ModeAll Mode = "all"
+	// ModeRead endpoint serves reads only
+	ModeRead Mode = "read"
+	// ModeSend endpoint only receives transaction broadcasts
+	ModeSend Mode = "send"
+)
+
+// Endpoint x
+type Endpoint struct {
+	URL    string
+	Weight int
+	Mode   Mode
+}
+
+// ParseEndpoint parses "url[;weight=N][;mode=all|read|send]"
+func ParseEndpoint(s string) (Endpoint, error) {
+	parts := strings.Split(s, ";")
+	e := Endpoint{
+		URL:    strings.TrimSpace(parts[0]),
+		Weight: 1,
+		Mode:   ModeAll,
+	}
+	if e.URL == "" {
+		return e, fmt.Errorf("endpoint %q: empty url", s)
+	}
+
+	for _, p := range parts[1:] {
+		kv := strings.SplitN(strings.TrimSpace(p), "=", 2)
+		if len(kv) != 2 {
+			return e, fmt.Errorf("endpoint %q: invalid option %q", s, p)
+		}
+		switch kv[0] {
+		case "weight":
+			w, err := strconv.Atoi(kv[1])
+			if err != nil || w <= 0 {
+				return e, fmt.Errorf("endpoint %q: invalid weight %q", s, kv[1])
+			}
+			e.Weight = w
+		case "mode":
+			switch Mode(kv[1]) {
+			case ModeAll, ModeRead, ModeSend:
+				e.Mode = Mode(kv[1])
+			default:
+				return e, fmt.Errorf("endpoint %q: invalid mode %q", s, kv[1])
+			}
+		default:
+			return e, fmt.Errorf("endpoint %q: unknown option %q", s, kv[0])
+		}
+	}
+
+	return e, nil
+}
+
+type endpoint struct {
+	Endpoint
+	client    jsonrpc.RPCClient
+	failures  int
+	downUntil time.Time
+}
+
+// Pool routes reads to healthy endpoints and broadcasts sendTransaction to all send endpoints
+type Pool struct {
+	mu        sync.Mutex
+	endpoints []*endpoint
+}
+
+// New x
+func New(endpoints ...Endpoint) *Pool {
+	p := &Pool{}
+	for _, e := range endpoints {
+		if e.Weight <= 0 {
+			e.Weight = 1
+		}
+		if e.Mode == "" {
+			e.Mode = ModeAll
+		}
+		p.endpoints = append(p.endpoints, &endpoint{
+			Endpoint: e,
+			client:   jsonrpc.NewClient(e.URL),
+		})
+	}
+	return p
+}
+
+// readers returns read endpoints, healthy ones first in weighted random order
+func (p *Pool) readers() []*endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	type candidate struct {
+		e     *endpoint
+		down  bool
+		score float64
+	}
+	candidates := []candidate{}
+	for _, e := range p.endpoints {
+		if e.Mode == ModeSend {
+			continue
+		}
+		candidates = append(candidates, candidate{
+			e:    e,
+			down: now.Before(e.downUntil),
+			// Weighted random order, see Efraimidis-Spirakis
+			score: rand.Float64() * float64(e.Weight),
+		})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].down != candidates[j].down {
+			return !candidates[i].down
+		}
+		return candidates[i].score > candidates[j].score
+	})
+
+	res := make([]*endpoint, 0, len(candidates))
+	for _, c := range candidates {
+		res = append(res, c.e)
+	}
+	return res
+}
+
+func (p *Pool) senders() []*endpoint {
+	res := []*endpoint{}
+	for _, e := range p.endpoints {
+		if e.Mode != ModeRead {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+func (p *Pool) markFailure(e *endpoint, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e.failures++
+	cooldown := minCooldown << uint(e.failures-1)
+	if cooldown > maxCooldown || cooldown <= 0 {
+		cooldown = maxCooldown
+	}
+	e.downUntil = time.Now().Add(cooldown)
+	log.Printf("rpc %v failed (%v), cooldown %v", e.URL, err, cooldown)
+}
+
+func (p *Pool) markSuccess(e *endpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e.failures = 0
+	e.downUntil = time.Time{}
+}
+
+// isEndpointFailure reports whether err means the endpoint itself is unhealthy,
+// json-rpc errors are answers from a working node and are returned as is
+func isEndpointFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var rpcErr *jsonrpc.RPCError
+	return !errors.As(err, &rpcErr)
+}
+
+func (p *Pool) read(ctx context.Context, call func(jsonrpc.RPCClient) error) error {
+	endpoints := p.readers()
+	if len(endpoints) == 0 {
+		return ErrNoEndpoints
+	}
+
+	var err error
+	for _, e := range endpoints {
+		err = call(e.client)
+		if !isEndpointFailure(err) {
+			p.markSuccess(e)
+			return err
+		}
+		// A cancelled call says nothing about the endpoint
+		if ctx.Err() != nil {
+			return err
+		}
+		p.markFailure(e, err)
+	}
+	return err
+}
+
+// CallForInto x
+func (p *Pool) CallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	if method == "sendTransaction" {
+		return p.broadcast(ctx, out, method, params)
+	}
+	return p.read(ctx, func(c jsonrpc.RPCClient) error {
+		return c.CallForInto(ctx, out, method, params)
+	})
+}
+
+// CallWithCallback x
+func (p *Pool) CallWithCallback(
+	ctx context.Context,
+	method string,
+	params []interface{},
+	callback func(*http.Request, *http.Response) error,
+) error {
+	return p.read(ctx, func(c jsonrpc.RPCClient) error {
+		return c.CallWithCallback(ctx, method, params, callback)
+	})
+}
+
+// CallBatch x
+func (p *Pool) CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	var res jsonrpc.RPCResponses
+	err := p.read(ctx, func(c jsonrpc.RPCClient) error {
+		var err error
+		res, err = c.CallBatch(ctx, requests)
+		return err
+	})
+	return res, err
+}
+
+type broadcastResult struct {
+	e   *endpoint
+	raw json.RawMessage
+	err error
+}
+
+// broadcast sends to every send endpoint in parallel and returns the first
+// successful result, the other endpoints finish in the background and only
+// update endpoint health, a signature different from the returned one is logged
+func (p *Pool) broadcast(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	endpoints := p.senders()
+	if len(endpoints) == 0 {
+		return ErrNoEndpoints
+	}
+
+	// Slower endpoints keep sending after the first success is returned
+	bctx, cancel := context.WithTimeout(context.Background(), broadcastTimeout)
+
+	results := make(chan broadcastResult, len(endpoints))
+	for _, e := range endpoints {
+		go func(e *endpoint) {
+			var raw json.RawMessage
+			err := e.client.CallForInto(bctx, &raw, method, params)
+			results <- broadcastResult{e: e, raw: raw, err: err}
+		}(e)
+	}
+
+	var firstErr error
+	for pending := len(endpoints); pending > 0; {
+		select {
+		case r := <-results:
+			pending--
+			if !p.handleBroadcastResult(r, "") {
+				if firstErr == nil {
+					firstErr = r.err
+				}
+				continue
+			}
+			go p.drain(results, pending, string(r.raw), cancel)
+			return json.Unmarshal(r.raw, out)
+		case <-ctx.Done():
+			go p.drain(results, pending, "", cancel)
+			return ctx.Err()
+		}
+	}
+
+	cancel()
+	return firstErr
+}
+
+// handleBroadcastResult updates endpoint health and reports whether r succeeded,
+// a success with a signature other than sig is logged and still counts
+func (p *Pool) handleBroadcastResult(r broadcastResult, sig string) bool {
+	if r.err != nil {
+		if isEndpointFailure(r.err) {
+			p.markFailure(r.e, r.err)
+		}
+		return false
+	}
+	p.markSuccess(r.e)
+	if sig != "" && sig != string(r.raw) {
+		log.Printf("rpc %v returned a different signature %s, expected %s", r.e.URL, r.raw, sig)
+	}
+	return true
+}
+
+func (p *Pool) drain(results chan broadcastResult, pending int, sig string, cancel context.CancelFunc) {
+	defer cancel()
+	for ; pending > 0; pending-- {
+		r := <-results
+		if p.handleBroadcastResult(r, sig) && sig == "" {
+			sig = string(r.raw)
+		}
+	}
+}
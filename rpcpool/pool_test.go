@@ -0,0 +1,289 @@
+package rpcpool
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseEndpoint(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Endpoint
+		wantErr bool
+	}{
+		{in: "https://a.example", want: Endpoint{URL: "https://a.example", Weight: 1, Mode: ModeAll}},
+		{in: " https://a.example ", want: Endpoint{URL: "https://a.example", Weight: 1, Mode: ModeAll}},
+		{in: "https://a.example;weight=3", want: Endpoint{URL: "https://a.example", Weight: 3, Mode: ModeAll}},
+		{in: "https://a.example;mode=send", want: Endpoint{URL: "https://a.example", Weight: 1, Mode: ModeSend}},
+		{in: "https://a.example; weight=2 ;mode=read", want: Endpoint{URL: "https://a.example", Weight: 2, Mode: ModeRead}},
+		{in: "", wantErr: true},
+		{in: ";weight=2", wantErr: true},
+		{in: "https://a.example;weight=0", wantErr: true},
+		{in: "https://a.example;weight=x", wantErr: true},
+		{in: "https://a.example;mode=write", wantErr: true},
+		{in: "https://a.example;timeout=1", wantErr: true},
+		{in: "https://a.example;weight", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseEndpoint(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseEndpoint(%q) = %+v, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseEndpoint(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseEndpoint(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// countingServer answers every request with result or status and counts the calls
+func countingServer(t *testing.T, status int, result interface{}) (string, func() int, func()) {
+	var mu sync.Mutex
+	calls := 0
+	server := testServer(t, func(method string, params []json.RawMessage) (interface{}, int) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return result, status
+	})
+	return server.URL, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}, server.Close
+}
+
+// health returns the failures and cooldown end of the endpoint with url
+func health(p *Pool, url string) (int, time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, e := range p.endpoints {
+		if e.URL == url {
+			return e.failures, e.downUntil
+		}
+	}
+	return 0, time.Time{}
+}
+
+func TestPoolWeightedReads(t *testing.T) {
+	heavy, heavyCalls, closeHeavy := countingServer(t, http.StatusOK, 1)
+	defer closeHeavy()
+	light, lightCalls, closeLight := countingServer(t, http.StatusOK, 1)
+	defer closeLight()
+	sender, senderCalls, closeSender := countingServer(t, http.StatusOK, 1)
+	defer closeSender()
+
+	pool := New(
+		Endpoint{URL: heavy, Weight: 9},
+		Endpoint{URL: light, Weight: 1},
+		Endpoint{URL: sender, Mode: ModeSend},
+	)
+	for i := 0; i < 200; i++ {
+		var out int
+		if err := pool.CallForInto(context.Background(), &out, "getSlot", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if senderCalls() != 0 {
+		t.Errorf("send endpoint served %v reads", senderCalls())
+	}
+	if heavyCalls()+lightCalls() != 200 {
+		t.Fatalf("%v + %v reads, want 200", heavyCalls(), lightCalls())
+	}
+	// The heavy endpoint goes first about 17 times out of 18
+	if heavyCalls() < 150 {
+		t.Errorf("weight 9 endpoint served %v of 200 reads", heavyCalls())
+	}
+}
+
+func TestPoolFailover(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		wantFailures int
+	}{
+		{name: "server error", status: http.StatusServiceUnavailable, wantFailures: 1},
+		{name: "rate limited", status: http.StatusTooManyRequests, wantFailures: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			down, downCalls, closeDown := countingServer(t, tt.status, nil)
+			defer closeDown()
+			up, upCalls, closeUp := countingServer(t, http.StatusOK, 1)
+			defer closeUp()
+
+			// The failing endpoint is always tried first until it cools down
+			pool := New(Endpoint{URL: down, Weight: 1000000}, Endpoint{URL: up})
+			for i := 0; i < 3; i++ {
+				var out int
+				if err := pool.CallForInto(context.Background(), &out, "getSlot", nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if downCalls() != 1 || upCalls() != 3 {
+				t.Fatalf("calls down %v up %v, want 1 and 3", downCalls(), upCalls())
+			}
+			failures, downUntil := health(pool, down)
+			if failures != tt.wantFailures {
+				t.Errorf("failures = %v, want %v", failures, tt.wantFailures)
+			}
+			if cooldown := time.Until(downUntil); cooldown <= 0 || cooldown > minCooldown {
+				t.Errorf("cooldown %v, want up to %v", cooldown, minCooldown)
+			}
+			if failures, _ := health(pool, up); failures != 0 {
+				t.Errorf("healthy endpoint failures = %v", failures)
+			}
+		})
+	}
+}
+
+func TestPoolCooldown(t *testing.T) {
+	down, _, closeDown := countingServer(t, http.StatusBadGateway, nil)
+	defer closeDown()
+
+	pool := New(Endpoint{URL: down})
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 1, want: minCooldown},
+		{failures: 2, want: 2 * minCooldown},
+		{failures: 3, want: 4 * minCooldown},
+	}
+
+	for _, tt := range tests {
+		// A down endpoint is still tried when no other is left
+		var out int
+		if err := pool.CallForInto(context.Background(), &out, "getSlot", nil); err == nil {
+			t.Fatal("want error")
+		}
+		failures, downUntil := health(pool, down)
+		if failures != tt.failures {
+			t.Fatalf("failures = %v, want %v", failures, tt.failures)
+		}
+		if cooldown := time.Until(downUntil); cooldown <= tt.want-time.Second/2 || cooldown > tt.want {
+			t.Errorf("failure %v: cooldown %v, want %v", tt.failures, cooldown, tt.want)
+		}
+	}
+
+	pool.markSuccess(pool.endpoints[0])
+	if failures, downUntil := health(pool, down); failures != 0 || !downUntil.IsZero() {
+		t.Errorf("after success failures = %v down until %v", failures, downUntil)
+	}
+}
+
+func TestPoolCancelledRead(t *testing.T) {
+	release := make(chan struct{})
+	slow := testServer(t, func(method string, params []json.RawMessage) (interface{}, int) {
+		<-release
+		return 1, http.StatusOK
+	})
+	defer slow.Close()
+	defer close(release)
+	other, otherCalls, closeOther := countingServer(t, http.StatusOK, 1)
+	defer closeOther()
+
+	pool := New(Endpoint{URL: slow.URL, Weight: 1000000}, Endpoint{URL: other})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var out int
+	if err := pool.CallForInto(ctx, &out, "getSlot", nil); err == nil {
+		t.Fatal("want error")
+	}
+	if failures, downUntil := health(pool, slow.URL); failures != 0 || !downUntil.IsZero() {
+		t.Errorf("cancelled endpoint failures = %v down until %v", failures, downUntil)
+	}
+	if otherCalls() != 0 {
+		t.Errorf("cancelled read went on to %v other calls", otherCalls())
+	}
+}
+
+func TestPoolBroadcast(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	slowCalls := 0
+	slow := testServer(t, func(method string, params []json.RawMessage) (interface{}, int) {
+		mu.Lock()
+		slowCalls++
+		mu.Unlock()
+		<-release
+		return nil, http.StatusServiceUnavailable
+	})
+	defer slow.Close()
+	fast, _, closeFast := countingServer(t, http.StatusOK, "sig")
+	defer closeFast()
+	failing, _, closeFailing := countingServer(t, http.StatusBadGateway, nil)
+	defer closeFailing()
+	reader, readerCalls, closeReader := countingServer(t, http.StatusOK, "other")
+	defer closeReader()
+
+	pool := New(
+		Endpoint{URL: slow.URL, Mode: ModeSend},
+		Endpoint{URL: fast},
+		Endpoint{URL: failing, Mode: ModeSend},
+		Endpoint{URL: reader, Mode: ModeRead},
+	)
+
+	var sig string
+	if err := pool.CallForInto(context.Background(), &sig, "sendTransaction", []interface{}{"tx"}); err != nil {
+		t.Fatal(err)
+	}
+	if sig != "sig" {
+		t.Fatalf("signature %q, want sig", sig)
+	}
+	if readerCalls() != 0 {
+		t.Errorf("read endpoint received %v broadcasts", readerCalls())
+	}
+
+	// The slow endpoint is still sending and its failure is recorded once it answers
+	close(release)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		slowFailures, _ := health(pool, slow.URL)
+		failingFailures, _ := health(pool, failing)
+		if slowFailures == 1 && failingFailures == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("broadcast results not drained")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	mu.Lock()
+	if slowCalls != 1 {
+		t.Errorf("slow endpoint received %v broadcasts", slowCalls)
+	}
+	mu.Unlock()
+}
+
+func TestPoolBroadcastAllFail(t *testing.T) {
+	a, _, closeA := countingServer(t, http.StatusBadGateway, nil)
+	defer closeA()
+	b, _, closeB := countingServer(t, http.StatusServiceUnavailable, nil)
+	defer closeB()
+
+	pool := New(Endpoint{URL: a, Mode: ModeSend}, Endpoint{URL: b, Mode: ModeSend})
+	var sig string
+	if err := pool.CallForInto(context.Background(), &sig, "sendTransaction", []interface{}{"tx"}); err == nil {
+		t.Fatalf("signature %q, want error", sig)
+	}
+	err := New(Endpoint{URL: a, Mode: ModeRead}).CallForInto(context.Background(), &sig, "sendTransaction", []interface{}{"tx"})
+	if err != ErrNoEndpoints {
+		t.Errorf("broadcast without send endpoints: %v, want %v", err, ErrNoEndpoints)
+	}
+}
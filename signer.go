@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/gagliardetto/solana-go"
+	"main/remotesigner"
+	"main/swap"
+)
+
+// remoteTokenEnv bearer token of the remote signing service
+const remoteTokenEnv = "REMOTE_SIGNER_TOKEN"
+
+type serveCmd struct {
+	Listen string `arg:"--listen" default:"127.0.0.1:7070" help:"address to listen on"`
+}
+
+// newSigner picks the remote signer, the keystore wallet or the wallet key, nil when none is set
+func newSigner(ctx context.Context, args cliArgs) swap.Signer {
+	switch {
+	case args.Remote != "":
+		owner := solana.PublicKey{}
+		if args.Owner != "" {
+			owner = mustPublicKey(args.Owner)
+		}
+		client, err := remotesigner.New(ctx, args.Remote, os.Getenv(remoteTokenEnv), owner)
+		if err != nil {
+			log.Fatalf("remote signer: %v", err)
+		}
+		return client
+	case args.Unlock != "":
+		return unlockSigner(args.KeyDir, args.Unlock)
+	case len(wallet) != 0:
+		return swap.NewPrivateKeySigner(wallet)
+	}
+	return nil
+}
+
+// newFeePayer returns the fee payer signer, nil when swaps are paid by the wallet
+func newFeePayer(args cliArgs) swap.Signer {
+	switch {
+	case args.FeePayerUnlock != "":
+		return unlockSigner(args.KeyDir, args.FeePayerUnlock)
+	case args.FeePayer != "":
+		key, err := loadWallet(args.FeePayer)
+		if err != nil {
+			log.Fatalf("fee payer key: %v", err)
+		}
+		return swap.NewPrivateKeySigner(key)
+	}
+	return nil
+}
+
+func doServe(ctx context.Context, args serveCmd) {
+	walletSigner := mustSigner()
+
+	// Without a token anyone reaching the address could sign with the wallet
+	token := os.Getenv(remoteTokenEnv)
+	if token == "" {
+		log.Fatalf("serve-signer requires a token in %v", remoteTokenEnv)
+	}
+
+	server := &http.Server{
+		Addr:    args.Listen,
+		Handler: remotesigner.NewHandler(token, walletSigner),
+	}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	log.Printf("serving %v on %v", walletSigner.PublicKey(), args.Listen)
+	err := server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("serve: %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package swap
+
+import (
+	"context"
+	"log"
+	"time"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Token2022ProgramID x
+var Token2022ProgramID = solana.MustPublicKeyFromBase58("TokenzQdBNbLqP5VEhdkAS6EPFLC1PHnBqCXEpPxuEb")
+
+// MaxCloseAccountsPerTransaction close instructions that fit one legacy transaction
+const MaxCloseAccountsPerTransaction = 20
+
+// tokenCloseAccount instruction number shared by Token and Token-2022
+const tokenCloseAccount = 9
+
+// TokenAccount token account of the owner with its balance
+type TokenAccount struct {
+	Address  solana.PublicKey
+	Program  solana.PublicKey
+	Mint     solana.PublicKey
+	Amount   uint64
+	Lamports uint64
+}
+
+// GetOwnerTokenAccounts lists the owner token accounts of the Token and Token-2022 programs
+func GetOwnerTokenAccounts(ctx context.Context, clientRPC *rpc.Client, owner solana.PublicKey) ([]TokenAccount, error) {
+	accounts := []TokenAccount{}
+
+	for _, program := range []solana.PublicKey{solana.TokenProgramID, Token2022ProgramID} {
+		ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+		res, err := clientRPC.GetTokenAccountsByOwner(
+			ctx,
+			owner,
+			&rpc.GetTokenAccountsConfig{ProgramId: program.ToPointer()},
+			&rpc.GetTokenAccountsOpts{
+				Commitment: rpc.CommitmentConfirmed,
+				Encoding:   solana.EncodingBase64,
+			},
+		)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, a := range res.Value {
+			// Token-2022 extensions follow the base account layout
+			var ta token.Account
+			err = bin.NewBinDecoder(a.Account.Data.GetBinary()).Decode(&ta)
+			if err != nil {
+				return nil, err
+			}
+			accounts = append(accounts, TokenAccount{
+				Address:  a.Pubkey,
+				Program:  program,
+				Mint:     ta.Mint,
+				Amount:   ta.Amount,
+				Lamports: a.Account.Lamports,
+			})
+		}
+	}
+
+	return accounts, nil
+}
+
+// NewCloseTokenAccountInstruction closes account of program sending its rent to owner
+func NewCloseTokenAccountInstruction(
+	program solana.PublicKey,
+	account solana.PublicKey,
+	owner solana.PublicKey,
+) solana.Instruction {
+	return solana.NewInstruction(
+		program,
+		solana.AccountMetaSlice{
+			solana.Meta(account).WRITE(),
+			solana.Meta(owner).WRITE(),
+			solana.Meta(owner).SIGNER(),
+		},
+		[]byte{tokenCloseAccount},
+	)
+}
+
+// CloseEmptyTokenAccounts closes the zero balance accounts in as few transactions as possible,
+// returns the confirmations, the lamports reclaimed by the landed ones and the accounts left open.
+// The accounts of a failed batch are retried one by one so one account that can not be closed,
+// frozen or holding withheld fees, does not keep the others open.
+func CloseEmptyTokenAccounts(
+	ctx context.Context,
+	clientRPC *rpc.Client,
+	signers []Signer,
+	accounts []TokenAccount,
+	cfg ExecuteConfig,
+) ([]*Confirmation, uint64, []TokenAccount, error) {
+	confs := []*Confirmation{}
+	unclosed := []TokenAccount{}
+	var reclaimed uint64
+
+	empty := []TokenAccount{}
+	for _, a := range accounts {
+		if a.Amount == 0 {
+			empty = append(empty, a)
+		}
+	}
+
+	for start := 0; start < len(empty); start += MaxCloseAccountsPerTransaction {
+		end := start + MaxCloseAccountsPerTransaction
+		if end > len(empty) {
+			end = len(empty)
+		}
+
+		batch := empty[start:end]
+		conf, err := closeTokenAccounts(ctx, clientRPC, signers, batch, cfg)
+		if err == nil {
+			confs = append(confs, conf)
+			reclaimed += sumLamports(batch)
+			continue
+		}
+		if ctx.Err() != nil {
+			return confs, reclaimed, append(unclosed, empty[start:]...), ctx.Err()
+		}
+		if len(batch) == 1 {
+			log.Printf("close %v: %v", batch[0].Address, err)
+			unclosed = append(unclosed, batch[0])
+			continue
+		}
+
+		log.Printf("close %v accounts: %v, retrying one by one", len(batch), err)
+		for i, a := range batch {
+			conf, err := closeTokenAccounts(ctx, clientRPC, signers, []TokenAccount{a}, cfg)
+			if err != nil {
+				if ctx.Err() != nil {
+					return confs, reclaimed, append(unclosed, empty[start+i:]...), ctx.Err()
+				}
+				log.Printf("close %v: %v", a.Address, err)
+				unclosed = append(unclosed, a)
+				continue
+			}
+			confs = append(confs, conf)
+			reclaimed += a.Lamports
+		}
+	}
+
+	return confs, reclaimed, unclosed, nil
+}
+
+func closeTokenAccounts(
+	ctx context.Context,
+	clientRPC *rpc.Client,
+	signers []Signer,
+	accounts []TokenAccount,
+	cfg ExecuteConfig,
+) (*Confirmation, error) {
+	owner := signers[0].PublicKey()
+	instrs := []solana.Instruction{}
+	for _, a := range accounts {
+		instrs = append(instrs, NewCloseTokenAccountInstruction(a.Program, a.Address, owner))
+	}
+	return ExecuteInstructionsAndWait(ctx, clientRPC, signers, cfg, instrs...)
+}
+
+func sumLamports(accounts []TokenAccount) uint64 {
+	var lamports uint64
+	for _, a := range accounts {
+		lamports += a.Lamports
+	}
+	return lamports
+}
@@ -0,0 +1,81 @@
+package swap
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+func TestCloseEmptyTokenAccounts(t *testing.T) {
+	const lamports = 2039280
+
+	accounts := []TokenAccount{}
+	for i := 0; i < 25; i++ {
+		accounts = append(accounts, TokenAccount{
+			Address:  solana.NewWallet().PublicKey(),
+			Program:  solana.TokenProgramID,
+			Mint:     solana.NewWallet().PublicKey(),
+			Lamports: lamports,
+		})
+	}
+	// Accounts holding tokens are left alone
+	accounts = append(accounts, TokenAccount{
+		Address: solana.NewWallet().PublicKey(),
+		Program: solana.TokenProgramID,
+		Amount:  1,
+	})
+
+	tests := []struct {
+		name         string
+		frozen       []int
+		wantConfs    int
+		wantUnclosed int
+	}{
+		{name: "all closed", wantConfs: 2},
+		{name: "frozen account in the first batch", frozen: []int{3}, wantConfs: 20, wantUnclosed: 1},
+		{name: "frozen accounts in both batches", frozen: []int{0, 22}, wantConfs: 23, wantUnclosed: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frozen := map[solana.PublicKey]bool{}
+			for _, i := range tt.frozen {
+				frozen[accounts[i].Address] = true
+			}
+			server := sendServer(t, func(tx *solana.Transaction) error {
+				for _, key := range tx.Message.AccountKeys {
+					if frozen[key] {
+						return errors.New("Account is frozen")
+					}
+				}
+				return nil
+			}, nil)
+			defer server.Close()
+
+			owner := NewPrivateKeySigner(solana.NewWallet().PrivateKey)
+			cfg := ExecuteConfig{RebroadcastInterval: time.Millisecond}
+			confs, reclaimed, unclosed, err := CloseEmptyTokenAccounts(context.Background(), rpc.New(server.URL), []Signer{owner}, accounts, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(confs) != tt.wantConfs {
+				t.Errorf("%v transactions, want %v", len(confs), tt.wantConfs)
+			}
+			if len(unclosed) != tt.wantUnclosed {
+				t.Fatalf("%v accounts not closed, want %v", len(unclosed), tt.wantUnclosed)
+			}
+			for _, a := range unclosed {
+				if !frozen[a.Address] {
+					t.Errorf("account %v not closed", a.Address)
+				}
+			}
+			if want := uint64(25-tt.wantUnclosed) * lamports; reclaimed != want {
+				t.Errorf("reclaimed %v, want %v", reclaimed, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package swap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+)
+
+// ErrInvalidCommitment x
+var ErrInvalidCommitment = errors.New("commitment must be processed, confirmed or finalized")
+
+// Confirmation x
+type Confirmation struct {
+	Signature solana.Signature
+	// Slot the transaction was confirmed in
+	Slot uint64
+	// Duration from first send to confirmation
+	Duration time.Duration
+}
+
+type signatureNotification struct {
+	slot  uint64
+	txErr interface{}
+	err   error
+}
+
+// subscribeSignature waits for sig over the rpc websocket, the channel
+// receives a single notification or an error when the socket drops
+func subscribeSignature(
+	ctx context.Context,
+	endpoint string,
+	sig solana.Signature,
+	commitment rpc.CommitmentType,
+) <-chan signatureNotification {
+	ch := make(chan signatureNotification, 1)
+
+	go func() {
+		client, err := ws.Connect(ctx, endpoint)
+		if err != nil {
+			ch <- signatureNotification{err: err}
+			return
+		}
+		defer client.Close()
+
+		sub, err := client.SignatureSubscribe(sig, commitment)
+		if err != nil {
+			ch <- signatureNotification{err: err}
+			return
+		}
+		defer sub.Unsubscribe()
+
+		select {
+		case res, ok := <-sub.Response():
+			if !ok || res == nil {
+				ch <- signatureNotification{err: ws.ErrTimeout}
+				return
+			}
+			ch <- signatureNotification{slot: res.Context.Slot, txErr: res.Value.Err}
+		case err := <-sub.Err():
+			ch <- signatureNotification{err: err}
+		case <-ctx.Done():
+			ch <- signatureNotification{err: ctx.Err()}
+		}
+	}()
+
+	return ch
+}
+
+var commitmentRank = map[rpc.ConfirmationStatusType]int{
+	rpc.ConfirmationStatusProcessed: 1,
+	rpc.ConfirmationStatusConfirmed: 2,
+	rpc.ConfirmationStatusFinalized: 3,
+}
+
+// ParseCommitment checks s is a commitment confirmations can wait for
+func ParseCommitment(s string) (rpc.CommitmentType, error) {
+	commitment := rpc.CommitmentType(s)
+	if _, ok := commitmentRank[rpc.ConfirmationStatusType(commitment)]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrInvalidCommitment, s)
+	}
+	return commitment, nil
+}
+
+// commitmentReached reports whether status is at least at commitment
+func commitmentReached(status *rpc.SignatureStatusesResult, commitment rpc.CommitmentType) bool {
+	if status == nil {
+		return false
+	}
+	// A failed transaction will not progress further
+	if status.Err != nil {
+		return true
+	}
+	return commitmentRank[status.ConfirmationStatus] >= commitmentRank[rpc.ConfirmationStatusType(commitment)]
+}
@@ -0,0 +1,240 @@
+package swap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// RaydiumLiquidityPoolV4ProgramID x
+var RaydiumLiquidityPoolV4ProgramID = solana.MustPublicKeyFromBase58("675kPX9MHTjS2zt1qfr1NYHuzeLXfQM9H24wFSUt1Mp8")
+
+var (
+	// ErrExceededSlippage swap output was below minimum out amount, retry with wider slippage
+	ErrExceededSlippage = errors.New("exceeded slippage")
+	// ErrInsufficientFunds source account or fee payer balance too low
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrInvalidStatus pool is not open for swaps
+	ErrInvalidStatus = errors.New("invalid pool status")
+	// ErrAccountAlreadyInUse account to create already exists
+	ErrAccountAlreadyInUse = errors.New("account already in use")
+	// ErrOwnerMismatch token account is not owned by the signer
+	ErrOwnerMismatch = errors.New("owner mismatch")
+	// ErrMintMismatch token account mint does not match
+	ErrMintMismatch = errors.New("mint mismatch")
+	// ErrAccountFrozen token account is frozen
+	ErrAccountFrozen = errors.New("account frozen")
+	// ErrNonNativeHasBalance non-native account has balance on close
+	ErrNonNativeHasBalance = errors.New("non-native account can only be closed if its balance is zero")
+	// ErrUnknownProgramError custom error code not known for its program
+	ErrUnknownProgramError = errors.New("unknown error")
+)
+
+// raydiumErrors AMM v4 custom error codes
+var raydiumErrors = map[uint32]error{
+	0:  ErrAccountAlreadyInUse,
+	1:  errors.New("invalid program address"),
+	2:  errors.New("expected mint"),
+	3:  errors.New("expected account"),
+	4:  errors.New("invalid coin vault"),
+	5:  errors.New("invalid pc vault"),
+	6:  errors.New("invalid lp token"),
+	7:  errors.New("invalid dest coin token"),
+	8:  errors.New("invalid dest pc token"),
+	9:  errors.New("invalid pool mint"),
+	10: errors.New("invalid open orders"),
+	11: errors.New("invalid serum market"),
+	12: errors.New("invalid serum program"),
+	13: errors.New("invalid target orders"),
+	14: errors.New("invalid withdraw queue"),
+	15: errors.New("invalid temp lp"),
+	16: errors.New("invalid coin mint"),
+	17: errors.New("invalid pc mint"),
+	18: ErrOwnerMismatch,
+	19: errors.New("invalid supply"),
+	20: errors.New("invalid delegate"),
+	21: errors.New("invalid sign account"),
+	22: ErrInvalidStatus,
+	23: errors.New("invalid instruction"),
+	24: errors.New("wrong accounts number"),
+	28: errors.New("invalid params set"),
+	29: errors.New("invalid input"),
+	30: ErrExceededSlippage,
+	31: errors.New("calculation exchange rate failure"),
+	32: errors.New("checked sub overflow"),
+	33: errors.New("checked add overflow"),
+	34: errors.New("checked mul overflow"),
+	35: errors.New("checked div overflow"),
+	36: errors.New("empty funds"),
+	38: errors.New("invalid spl token program"),
+	40: ErrInsufficientFunds,
+	41: errors.New("conversion failure"),
+	42: errors.New("invalid user token"),
+}
+
+// tokenErrors SPL Token custom error codes
+var tokenErrors = map[uint32]error{
+	0:  errors.New("lamport balance below rent-exempt threshold"),
+	1:  ErrInsufficientFunds,
+	2:  errors.New("invalid mint"),
+	3:  ErrMintMismatch,
+	4:  ErrOwnerMismatch,
+	5:  errors.New("fixed supply"),
+	6:  ErrAccountAlreadyInUse,
+	7:  errors.New("invalid number of provided signers"),
+	8:  errors.New("invalid number of required signers"),
+	9:  errors.New("state is uninitialized"),
+	10: errors.New("instruction does not support native tokens"),
+	11: ErrNonNativeHasBalance,
+	12: errors.New("invalid instruction"),
+	13: errors.New("state is invalid for requested operation"),
+	14: errors.New("operation overflowed"),
+	15: errors.New("account does not support specified authority type"),
+	16: errors.New("this token mint cannot freeze accounts"),
+	17: ErrAccountFrozen,
+	18: errors.New("mint decimals mismatch"),
+	19: errors.New("instruction does not support non-native tokens"),
+}
+
+// associatedTokenErrors ATA program custom error codes
+var associatedTokenErrors = map[uint32]error{
+	0: errors.New("associated token account owner does not match address derivation"),
+}
+
+// systemErrors System program custom error codes
+var systemErrors = map[uint32]error{
+	0: ErrAccountAlreadyInUse,
+	1: ErrInsufficientFunds,
+	2: errors.New("cannot assign account to this program id"),
+	3: errors.New("cannot allocate account data of this length"),
+	4: errors.New("length of requested seed is too long"),
+	5: errors.New("provided address does not match addressed derived from seed"),
+	6: errors.New("advancing stored nonce requires a populated RecentBlockhashes sysvar"),
+	7: errors.New("stored nonce is still in recent_blockhashes"),
+	8: errors.New("specified nonce does not match stored nonce"),
+}
+
+var programErrors = map[solana.PublicKey]map[uint32]error{
+	RaydiumLiquidityPoolV4ProgramID:           raydiumErrors,
+	solana.TokenProgramID:                     tokenErrors,
+	solana.SPLAssociatedTokenAccountProgramID: associatedTokenErrors,
+	solana.SystemProgramID:                    systemErrors,
+}
+
+// transactionErrors top level transaction errors with a matching sentinel
+var transactionErrors = map[string]error{
+	"InsufficientFundsForFee":  ErrInsufficientFunds,
+	"InsufficientFundsForRent": ErrInsufficientFunds,
+	"AccountInUse":             ErrAccountAlreadyInUse,
+}
+
+// ProgramError failed instruction decoded from a transaction error
+type ProgramError struct {
+	Program     solana.PublicKey
+	Instruction int
+	// Code custom program error code, -1 for builtin instruction errors
+	Code int64
+	Err  error
+}
+
+// Error x
+func (e *ProgramError) Error() string {
+	if e.Code < 0 {
+		return fmt.Sprintf("instruction %d (%v): %v", e.Instruction, e.Program, e.Err)
+	}
+	return fmt.Sprintf("instruction %d (%v): custom error %d: %v", e.Instruction, e.Program, e.Code, e.Err)
+}
+
+// Unwrap x
+func (e *ProgramError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeTransactionError converts an rpc transaction error into a Go error usable with errors.Is
+func DecodeTransactionError(tx *solana.Transaction, txErr interface{}) error {
+	switch v := txErr.(type) {
+	case nil:
+		return nil
+	case string:
+		if err, ok := transactionErrors[v]; ok {
+			return fmt.Errorf("%s: %w", v, err)
+		}
+		return errors.New(v)
+	case map[string]interface{}:
+		if ie, ok := v["InstructionError"]; ok {
+			return decodeInstructionError(tx, ie)
+		}
+		for name := range v {
+			if err, ok := transactionErrors[name]; ok {
+				return fmt.Errorf("%v: %w", v, err)
+			}
+		}
+	}
+	return fmt.Errorf("%v", txErr)
+}
+
+func decodeInstructionError(tx *solana.Transaction, ie interface{}) error {
+	parts, ok := ie.([]interface{})
+	if !ok || len(parts) != 2 {
+		return fmt.Errorf("InstructionError: %v", ie)
+	}
+
+	idx, ok := toInt64(parts[0])
+	if !ok {
+		return fmt.Errorf("InstructionError: %v", ie)
+	}
+
+	pErr := &ProgramError{
+		Instruction: int(idx),
+		Code:        -1,
+	}
+
+	if tx != nil && int(idx) < len(tx.Message.Instructions) {
+		programIdx := int(tx.Message.Instructions[idx].ProgramIDIndex)
+		if programIdx < len(tx.Message.AccountKeys) {
+			pErr.Program = tx.Message.AccountKeys[programIdx]
+		}
+	}
+
+	switch detail := parts[1].(type) {
+	case string:
+		pErr.Err = errors.New(detail)
+		if detail == "InsufficientFunds" {
+			pErr.Err = ErrInsufficientFunds
+		}
+	case map[string]interface{}:
+		code, ok := toInt64(detail["Custom"])
+		if !ok {
+			pErr.Err = fmt.Errorf("%v", detail)
+			break
+		}
+		pErr.Code = code
+		pErr.Err = ErrUnknownProgramError
+		if known, ok := programErrors[pErr.Program][uint32(code)]; ok {
+			pErr.Err = known
+		}
+	default:
+		pErr.Err = fmt.Errorf("%v", detail)
+	}
+
+	return pErr
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return int64(n), true
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint64:
+		return int64(n), true
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,86 @@
+package swap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+func TestDecodeTransactionError(t *testing.T) {
+	payer := solana.NewWallet().PublicKey()
+	account := solana.NewWallet().PublicKey()
+	programs := []solana.PublicKey{
+		RaydiumLiquidityPoolV4ProgramID,
+		solana.TokenProgramID,
+		solana.SystemProgramID,
+	}
+	instrs := []solana.Instruction{}
+	for _, program := range programs {
+		instrs = append(instrs, solana.NewInstruction(program, solana.AccountMetaSlice{solana.Meta(account).WRITE()}, []byte{0}))
+	}
+	tx, err := solana.NewTransaction(instrs, solana.Hash{}, solana.TransactionPayer(payer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// instructionError as decoded from an rpc response
+	instructionError := func(idx int, detail string) interface{} {
+		var v interface{}
+		data := fmt.Sprintf(`{"InstructionError":[%d,%s]}`, idx, detail)
+		if err := json.Unmarshal([]byte(data), &v); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	// program is nil for top level transaction errors
+	tests := []struct {
+		name    string
+		txErr   interface{}
+		want    error
+		program *solana.PublicKey
+		code    int64
+	}{
+		{"nil", nil, nil, nil, 0},
+		{"raydium slippage", instructionError(0, `{"Custom":30}`), ErrExceededSlippage, &RaydiumLiquidityPoolV4ProgramID, 30},
+		{"raydium unknown code", instructionError(0, `{"Custom":999}`), ErrUnknownProgramError, &RaydiumLiquidityPoolV4ProgramID, 999},
+		{"token insufficient funds", instructionError(1, `{"Custom":1}`), ErrInsufficientFunds, &solana.TokenProgramID, 1},
+		{"token non native balance", instructionError(1, `{"Custom":11}`), ErrNonNativeHasBalance, &solana.TokenProgramID, 11},
+		{"system account in use", instructionError(2, `{"Custom":0}`), ErrAccountAlreadyInUse, &solana.SystemProgramID, 0},
+		{"builtin insufficient funds", instructionError(2, `"InsufficientFunds"`), ErrInsufficientFunds, &solana.SystemProgramID, -1},
+		{"fee", "InsufficientFundsForFee", ErrInsufficientFunds, nil, 0},
+		{"rent", map[string]interface{}{"InsufficientFundsForRent": map[string]interface{}{"account_index": 0.0}}, ErrInsufficientFunds, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DecodeTransactionError(tx, tt.txErr)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("err = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+
+			var pErr *ProgramError
+			if tt.program == nil {
+				if errors.As(err, &pErr) {
+					t.Fatalf("unexpected program error %v", pErr)
+				}
+				return
+			}
+			if !errors.As(err, &pErr) {
+				t.Fatalf("err = %v, want a ProgramError", err)
+			}
+			if !pErr.Program.Equals(*tt.program) || pErr.Code != tt.code {
+				t.Errorf("program %v code %v, want %v code %v", pErr.Program, pErr.Code, *tt.program, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package swap
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/rpc"
+	"main/models"
+)
+
+// DefaultFeePercentile used by auto priority fee when percentile is not set
+const DefaultFeePercentile = 75.0
+
+// PriorityFeeConfig compute budget settings prepended to a swap
+type PriorityFeeConfig struct {
+	// UnitPrice in micro-lamports per compute unit, ignored when Auto is set
+	UnitPrice uint64
+	// UnitLimit compute units requested for the transaction, zero keeps the runtime default
+	UnitLimit uint32
+	// Auto derives UnitPrice from getRecentPrioritizationFees
+	Auto bool
+	// Percentile of recent fees used in Auto mode
+	Percentile float64
+}
+
+// GetPriorityFee returns the percentile of recent prioritization fees paid for accounts
+func GetPriorityFee(
+	ctx context.Context,
+	clientRPC *rpc.Client,
+	percentile float64,
+	accounts ...solana.PublicKey,
+) (uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+
+	res, err := clientRPC.GetRecentPrioritizationFees(ctx, accounts)
+	if err != nil {
+		return 0, err
+	}
+
+	fees := make([]uint64, 0, len(res))
+	for _, r := range res {
+		fees = append(fees, r.PrioritizationFee)
+	}
+
+	return feePercentile(fees, percentile), nil
+}
+
+func feePercentile(fees []uint64, percentile float64) uint64 {
+	if len(fees) == 0 {
+		return 0
+	}
+	if percentile <= 0 {
+		percentile = DefaultFeePercentile
+	}
+	if percentile > 100 {
+		percentile = 100
+	}
+
+	sort.Slice(fees, func(i, j int) bool { return fees[i] < fees[j] })
+
+	idx := int(float64(len(fees)-1) * percentile / 100.0)
+	return fees[idx]
+}
+
+// NewComputeBudgetInstructions builds compute unit limit and price instructions for cfg
+func NewComputeBudgetInstructions(
+	ctx context.Context,
+	clientRPC *rpc.Client,
+	cfg PriorityFeeConfig,
+	accounts ...solana.PublicKey,
+) ([]solana.Instruction, error) {
+	instrs := []solana.Instruction{}
+
+	if cfg.UnitLimit > 0 {
+		inst, err := computebudget.NewSetComputeUnitLimitInstruction(cfg.UnitLimit).ValidateAndBuild()
+		if err != nil {
+			return nil, err
+		}
+		instrs = append(instrs, inst)
+	}
+
+	price := cfg.UnitPrice
+	if cfg.Auto {
+		fee, err := GetPriorityFee(ctx, clientRPC, cfg.Percentile, accounts...)
+		if err != nil {
+			return nil, err
+		}
+		price = fee
+	}
+
+	if price > 0 {
+		inst, err := computebudget.NewSetComputeUnitPriceInstruction(price).ValidateAndBuild()
+		if err != nil {
+			return nil, err
+		}
+		instrs = append(instrs, inst)
+	}
+
+	return instrs, nil
+}
+
+// PoolWritableAccounts accounts locked for write by a swap on pool
+func PoolWritableAccounts(pool *models.PoolConfig) []solana.PublicKey {
+	return []solana.PublicKey{
+		solana.MustPublicKeyFromBase58(pool.ID),
+		solana.MustPublicKeyFromBase58(pool.OpenOrders),
+		solana.MustPublicKeyFromBase58(pool.TargetOrders),
+		solana.MustPublicKeyFromBase58(pool.BaseVault),
+		solana.MustPublicKeyFromBase58(pool.QuoteVault),
+		solana.MustPublicKeyFromBase58(pool.MarketID),
+		solana.MustPublicKeyFromBase58(pool.MarketBids),
+		solana.MustPublicKeyFromBase58(pool.MarketAsks),
+		solana.MustPublicKeyFromBase58(pool.MarketEventQueue),
+		solana.MustPublicKeyFromBase58(pool.MarketBaseVault),
+		solana.MustPublicKeyFromBase58(pool.MarketQuoteVault),
+	}
+}
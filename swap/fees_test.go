@@ -0,0 +1,29 @@
+package swap
+
+import "testing"
+
+func TestFeePercentile(t *testing.T) {
+	tests := []struct {
+		name       string
+		fees       []uint64
+		percentile float64
+		want       uint64
+	}{
+		{"empty", nil, 75, 0},
+		{"single", []uint64{42}, 75, 42},
+		{"median", []uint64{5, 1, 4, 2, 3}, 50, 3},
+		{"unsorted 75th", []uint64{40, 10, 30, 20, 50}, 75, 40},
+		{"max", []uint64{3, 1, 2}, 100, 3},
+		{"above 100 clamps", []uint64{3, 1, 2}, 150, 3},
+		{"zero uses default", []uint64{40, 10, 30, 20, 50}, 0, 40},
+		{"min", []uint64{3, 1, 2}, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := feePercentile(tt.fees, tt.percentile); got != tt.want {
+				t.Errorf("feePercentile(%v, %v) = %v, want %v", tt.fees, tt.percentile, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package swap
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	addresslookuptable "github.com/gagliardetto/solana-go/programs/address-lookup-table"
+	"github.com/gagliardetto/solana-go/rpc"
+	"main/models"
+)
+
+// AddressLookupTableProgramID x
+var AddressLookupTableProgramID = solana.MustPublicKeyFromBase58("AddressLookupTab1e1111111111111111111111111")
+
+const (
+	lookupTableCreate uint32 = 0
+	lookupTableExtend uint32 = 2
+)
+
+// NewCreateLookupTableInstruction returns the create instruction and the derived table address
+func NewCreateLookupTableInstruction(
+	authority solana.PublicKey,
+	payer solana.PublicKey,
+	recentSlot uint64,
+) (solana.Instruction, solana.PublicKey, error) {
+	slot := make([]byte, 8)
+	binary.LittleEndian.PutUint64(slot, recentSlot)
+
+	table, bump, err := solana.FindProgramAddress(
+		[][]byte{authority.Bytes(), slot},
+		AddressLookupTableProgramID,
+	)
+	if err != nil {
+		return nil, solana.PublicKey{}, err
+	}
+
+	data := make([]byte, 0, 13)
+	data = binary.LittleEndian.AppendUint32(data, lookupTableCreate)
+	data = append(data, slot...)
+	data = append(data, bump)
+
+	inst := solana.NewInstruction(
+		AddressLookupTableProgramID,
+		solana.AccountMetaSlice{
+			solana.Meta(table).WRITE(),
+			solana.Meta(authority).SIGNER(),
+			solana.Meta(payer).WRITE().SIGNER(),
+			solana.Meta(solana.SystemProgramID),
+		},
+		data,
+	)
+
+	return inst, table, nil
+}
+
+// NewExtendLookupTableInstruction appends addresses to table
+func NewExtendLookupTableInstruction(
+	table solana.PublicKey,
+	authority solana.PublicKey,
+	payer solana.PublicKey,
+	addresses ...solana.PublicKey,
+) solana.Instruction {
+	data := make([]byte, 0, 12+32*len(addresses))
+	data = binary.LittleEndian.AppendUint32(data, lookupTableExtend)
+	data = binary.LittleEndian.AppendUint64(data, uint64(len(addresses)))
+	for _, a := range addresses {
+		data = append(data, a.Bytes()...)
+	}
+
+	return solana.NewInstruction(
+		AddressLookupTableProgramID,
+		solana.AccountMetaSlice{
+			solana.Meta(table).WRITE(),
+			solana.Meta(authority).SIGNER(),
+			solana.Meta(payer).WRITE().SIGNER(),
+			solana.Meta(solana.SystemProgramID),
+		},
+		data,
+	)
+}
+
+// GetAddressTables resolves lookup table accounts to their addresses
+func GetAddressTables(
+	ctx context.Context,
+	clientRPC *rpc.Client,
+	tables ...solana.PublicKey,
+) (map[solana.PublicKey]solana.PublicKeySlice, error) {
+	res := map[solana.PublicKey]solana.PublicKeySlice{}
+	if len(tables) == 0 {
+		return res, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	accounts, err := clientRPC.GetMultipleAccounts(ctx, tables...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, a := range accounts.Value {
+		if a == nil {
+			return nil, fmt.Errorf("lookup table %v not found", tables[i])
+		}
+		state, err := addresslookuptable.DecodeAddressLookupTableState(a.Data.GetBinary())
+		if err != nil {
+			return nil, fmt.Errorf("decoding lookup table %v: %w", tables[i], err)
+		}
+		res[tables[i]] = state.Addresses
+	}
+
+	return res, nil
+}
+
+// PoolStaticAccounts accounts used by every swap on pool, independent of the user
+func PoolStaticAccounts(pool *models.PoolConfig) []solana.PublicKey {
+	accounts := []solana.PublicKey{}
+	seen := map[solana.PublicKey]bool{}
+	for _, meta := range NewRaydiumSwapInstruction(0, 0, pool, solana.PublicKey{}, solana.PublicKey{}, solana.PublicKey{}).Accounts() {
+		if meta.IsSigner || meta.PublicKey.IsZero() || seen[meta.PublicKey] {
+			continue
+		}
+		seen[meta.PublicKey] = true
+		accounts = append(accounts, meta.PublicKey)
+	}
+	return accounts
+}
+
+// CreatePoolLookupTable creates a lookup table holding the pool static accounts,
+// or extends table with the missing ones when it is set
+func CreatePoolLookupTable(
+	ctx context.Context,
+	clientRPC *rpc.Client,
+	signers []Signer,
+	pool *models.PoolConfig,
+	table solana.PublicKey,
+	cfg ExecuteConfig,
+) (solana.PublicKey, *Confirmation, error) {
+	authority := signers[0].PublicKey()
+	instrs := []solana.Instruction{}
+	addresses := PoolStaticAccounts(pool)
+
+	if table.IsZero() {
+		ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+		defer cancel()
+
+		slot, err := clientRPC.GetSlot(ctx, rpc.CommitmentFinalized)
+		if err != nil {
+			return table, nil, err
+		}
+
+		inst, address, err := NewCreateLookupTableInstruction(authority, authority, slot)
+		if err != nil {
+			return table, nil, err
+		}
+		instrs = append(instrs, inst)
+		table = address
+	} else {
+		existing, err := GetAddressTables(ctx, clientRPC, table)
+		if err != nil {
+			return table, nil, err
+		}
+		known := map[solana.PublicKey]bool{}
+		for _, a := range existing[table] {
+			known[a] = true
+		}
+		missing := []solana.PublicKey{}
+		for _, a := range addresses {
+			if !known[a] {
+				missing = append(missing, a)
+			}
+		}
+		addresses = missing
+	}
+
+	if len(addresses) == 0 {
+		return table, nil, nil
+	}
+
+	instrs = append(instrs, NewExtendLookupTableInstruction(table, authority, authority, addresses...))
+
+	// The table does not exist yet, it can not be used for its own transaction
+	cfg.LookupTables = nil
+	conf, err := ExecuteInstructionsAndWait(ctx, clientRPC, signers, cfg, instrs...)
+	if err != nil {
+		return table, conf, err
+	}
+
+	return table, conf, nil
+}
@@ -0,0 +1,91 @@
+package swap
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+func TestPoolStaticAccounts(t *testing.T) {
+	pool := testPool(solana.NewWallet().PublicKey(), solana.SolMint)
+	accounts := PoolStaticAccounts(pool)
+
+	seen := map[solana.PublicKey]bool{}
+	for _, a := range accounts {
+		if a.IsZero() {
+			t.Errorf("zero account in table")
+		}
+		if seen[a] {
+			t.Errorf("duplicate account %v", a)
+		}
+		seen[a] = true
+	}
+
+	// Every account of the pool and its market goes in the table
+	for _, key := range []string{pool.ID, pool.OpenOrders, pool.TargetOrders, pool.BaseVault, pool.QuoteVault,
+		pool.MarketID, pool.MarketBids, pool.MarketAsks, pool.MarketEventQueue, pool.MarketBaseVault, pool.MarketQuoteVault} {
+		if !seen[solana.MustPublicKeyFromBase58(key)] {
+			t.Errorf("pool account %v missing", key)
+		}
+	}
+	if !seen[solana.TokenProgramID] {
+		t.Errorf("token program missing")
+	}
+}
+
+func TestLookupTableInstructions(t *testing.T) {
+	authority := solana.NewWallet().PublicKey()
+	payer := solana.NewWallet().PublicKey()
+
+	tests := []struct {
+		name      string
+		slot      uint64
+		addresses int
+	}{
+		{name: "one address", slot: 1, addresses: 1},
+		{name: "full extend", slot: 250000000, addresses: 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			create, table, err := NewCreateLookupTableInstruction(authority, payer, tt.slot)
+			if err != nil {
+				t.Fatal(err)
+			}
+			slot := binary.LittleEndian.AppendUint64(nil, tt.slot)
+			want, bump, err := solana.FindProgramAddress([][]byte{authority.Bytes(), slot}, AddressLookupTableProgramID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !table.Equals(want) {
+				t.Fatalf("table %v, want %v", table, want)
+			}
+			data, _ := create.Data()
+			if binary.LittleEndian.Uint32(data) != lookupTableCreate || binary.LittleEndian.Uint64(data[4:]) != tt.slot || data[12] != bump {
+				t.Fatalf("create data %x", data)
+			}
+
+			addresses := []solana.PublicKey{}
+			for i := 0; i < tt.addresses; i++ {
+				addresses = append(addresses, solana.NewWallet().PublicKey())
+			}
+			extend := NewExtendLookupTableInstruction(table, authority, payer, addresses...)
+			data, _ = extend.Data()
+			if len(data) != 12+32*tt.addresses {
+				t.Fatalf("extend data of %v bytes", len(data))
+			}
+			if binary.LittleEndian.Uint32(data) != lookupTableExtend || binary.LittleEndian.Uint64(data[4:]) != uint64(tt.addresses) {
+				t.Fatalf("extend header %x", data[:12])
+			}
+			for i, a := range addresses {
+				if solana.PublicKeyFromBytes(data[12+32*i:]) != a {
+					t.Fatalf("address %v not encoded", i)
+				}
+			}
+			if !extend.Accounts()[0].PublicKey.Equals(table) {
+				t.Fatalf("extend writes %v, want the table %v", extend.Accounts()[0].PublicKey, table)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package swap
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// NonceAccountSize x
+const NonceAccountSize = 80
+
+// nonceUninitialized state of a nonce account without a stored nonce
+const nonceUninitialized = 0
+
+// ErrNonceAdvanced nonce was used by another transaction before confirmation, safe to retry
+var ErrNonceAdvanced = errors.New("nonce advanced before confirmation, transaction did not land")
+
+// GetNonceAccount x
+func GetNonceAccount(ctx context.Context, clientRPC *rpc.Client, nonceAccount solana.PublicKey) (*system.NonceAccount, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	res, err := clientRPC.GetAccountInfoWithOpts(ctx, nonceAccount, &rpc.GetAccountInfoOpts{
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var nonce system.NonceAccount
+	err = bin.NewBinDecoder(res.Value.Data.GetBinary()).Decode(&nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return &nonce, nil
+}
+
+// CreateNonceAccount creates and initializes a new nonce account owned by authority
+func CreateNonceAccount(
+	ctx context.Context,
+	clientRPC *rpc.Client,
+	signers []Signer,
+	authority solana.PublicKey,
+	cfg ExecuteConfig,
+) (solana.PublicKey, *Confirmation, error) {
+	nonceAccount := solana.NewWallet()
+
+	rentCtx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	rentCost, err := clientRPC.GetMinimumBalanceForRentExemption(
+		rentCtx,
+		NonceAccountSize,
+		rpc.CommitmentConfirmed,
+	)
+	if err != nil {
+		return nonceAccount.PublicKey(), nil, err
+	}
+
+	createInst, err := system.NewCreateAccountInstruction(
+		rentCost,
+		NonceAccountSize,
+		solana.SystemProgramID,
+		signers[0].PublicKey(),
+		nonceAccount.PublicKey(),
+	).ValidateAndBuild()
+	if err != nil {
+		return nonceAccount.PublicKey(), nil, err
+	}
+
+	initInst, err := system.NewInitializeNonceAccountInstruction(
+		authority,
+		nonceAccount.PublicKey(),
+		solana.SysVarRecentBlockHashesPubkey,
+		solana.SysVarRentPubkey,
+	).ValidateAndBuild()
+	if err != nil {
+		return nonceAccount.PublicKey(), nil, err
+	}
+
+	// A new nonce account can not pay for its own creation
+	cfg.NonceAccount = solana.PublicKey{}
+	conf, err := ExecuteInstructionsAndWait(
+		ctx,
+		clientRPC,
+		append(signers, NewPrivateKeySigner(nonceAccount.PrivateKey)),
+		cfg,
+		createInst,
+		initInst,
+	)
+	return nonceAccount.PublicKey(), conf, err
+}
+
+// WithdrawNonceAccount moves lamports from the nonce account to recipient,
+// withdrawing the whole balance closes the account
+func WithdrawNonceAccount(
+	ctx context.Context,
+	clientRPC *rpc.Client,
+	signers []Signer,
+	nonceAccount solana.PublicKey,
+	recipient solana.PublicKey,
+	lamports uint64,
+	cfg ExecuteConfig,
+) (*Confirmation, error) {
+	if lamports == 0 {
+		ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+		defer cancel()
+
+		balance, err := clientRPC.GetBalance(ctx, nonceAccount, rpc.CommitmentConfirmed)
+		if err != nil {
+			return nil, err
+		}
+		lamports = balance.Value
+	}
+
+	inst, err := system.NewWithdrawNonceAccountInstruction(
+		lamports,
+		nonceAccount,
+		recipient,
+		solana.SysVarRecentBlockHashesPubkey,
+		solana.SysVarRentPubkey,
+		signers[0].PublicKey(),
+	).ValidateAndBuild()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.NonceAccount = solana.PublicKey{}
+	return ExecuteInstructionsAndWait(ctx, clientRPC, signers, cfg, inst)
+}
+
+// AdvanceNonceAccount stores a new nonce in nonceAccount, transactions signed with the
+// previous one can no longer land
+func AdvanceNonceAccount(
+	ctx context.Context,
+	clientRPC *rpc.Client,
+	signers []Signer,
+	nonceAccount solana.PublicKey,
+	cfg ExecuteConfig,
+) (*Confirmation, error) {
+	inst, err := system.NewAdvanceNonceAccountInstruction(
+		nonceAccount,
+		solana.SysVarRecentBlockHashesPubkey,
+		signers[0].PublicKey(),
+	).ValidateAndBuild()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.NonceAccount = solana.PublicKey{}
+	return ExecuteInstructionsAndWait(ctx, clientRPC, signers, cfg, inst)
+}
+
+// BuildNonceTransacion builds a transaction using the stored nonce of nonceAccount as blockhash,
+// it does not expire until the nonce is advanced
+func BuildNonceTransacion(
+	ctx context.Context,
+	clientRPC *rpc.Client,
+	signers []Signer,
+	tables map[solana.PublicKey]solana.PublicKeySlice,
+	nonceAccount solana.PublicKey,
+	instrs ...solana.Instruction,
+) (*solana.Transaction, error) {
+	nonce, instrs, err := withAdvanceNonce(ctx, clientRPC, nonceAccount, instrs...)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := newTransaction(signers[0].PublicKey(), nonce, tables, instrs...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = SignTransaction(ctx, tx, signers)
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// withAdvanceNonce returns the stored nonce and instrs prefixed with AdvanceNonceAccount
+func withAdvanceNonce(
+	ctx context.Context,
+	clientRPC *rpc.Client,
+	nonceAccount solana.PublicKey,
+	instrs ...solana.Instruction,
+) (solana.Hash, []solana.Instruction, error) {
+	nonce, err := GetNonceAccount(ctx, clientRPC, nonceAccount)
+	if err != nil {
+		return solana.Hash{}, nil, err
+	}
+
+	advanceInst, err := system.NewAdvanceNonceAccountInstruction(
+		nonceAccount,
+		solana.SysVarRecentBlockHashesPubkey,
+		nonce.AuthorizedPubkey,
+	).ValidateAndBuild()
+	if err != nil {
+		return solana.Hash{}, nil, err
+	}
+
+	// AdvanceNonceAccount must be the first instruction
+	return solana.Hash(nonce.Nonce), append([]solana.Instruction{advanceInst}, instrs...), nil
+}
+
+// NonceAccountOf returns the nonce account advanced by the first instruction of tx
+func NonceAccountOf(tx *solana.Transaction) (solana.PublicKey, bool) {
+	if len(tx.Message.Instructions) == 0 {
+		return solana.PublicKey{}, false
+	}
+
+	inst := tx.Message.Instructions[0]
+	program, err := tx.Message.Program(inst.ProgramIDIndex)
+	if err != nil || !program.Equals(solana.SystemProgramID) {
+		return solana.PublicKey{}, false
+	}
+	if len(inst.Data) < 4 || binary.LittleEndian.Uint32(inst.Data) != system.Instruction_AdvanceNonceAccount {
+		return solana.PublicKey{}, false
+	}
+	if len(inst.Accounts) == 0 || int(inst.Accounts[0]) >= len(tx.Message.AccountKeys) {
+		return solana.PublicKey{}, false
+	}
+
+	return tx.Message.AccountKeys[inst.Accounts[0]], true
+}
+
+// nonceAdvanced reports whether the nonce stored in nonceAccount is no longer blockhash,
+// a closed nonce account can not be advanced by a transaction anymore
+func nonceAdvanced(ctx context.Context, clientRPC *rpc.Client, blockhash solana.Hash, nonceAccount solana.PublicKey) (bool, error) {
+	nonce, err := GetNonceAccount(ctx, clientRPC, nonceAccount)
+	if errors.Is(err, rpc.ErrNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if nonce.State == nonceUninitialized {
+		return true, nil
+	}
+	return solana.Hash(nonce.Nonce) != blockhash, nil
+}
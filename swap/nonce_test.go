@@ -0,0 +1,122 @@
+package swap
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+func TestBuildNonceTransaction(t *testing.T) {
+	owner := solana.NewWallet().PrivateKey
+	nonceAccount := solana.NewWallet().PublicKey()
+	nonce := solana.Hash{9}
+
+	memo := func(text string) solana.Instruction {
+		return solana.NewInstruction(solana.MemoProgramID, solana.AccountMetaSlice{}, []byte(text))
+	}
+	unitLimit := computebudget.NewSetComputeUnitLimitInstruction(200000).Build()
+	unitPrice := computebudget.NewSetComputeUnitPriceInstruction(1000).Build()
+
+	tests := []struct {
+		name      string
+		authority solana.PublicKey
+		instrs    []solana.Instruction
+		wantErr   error
+	}{
+		{name: "single instruction", authority: owner.PublicKey(), instrs: []solana.Instruction{memo("a")}},
+		{name: "compute budget stays after advance", authority: owner.PublicKey(), instrs: []solana.Instruction{unitLimit, unitPrice, memo("a"), memo("b")}},
+		{name: "authority not a signer", authority: solana.NewWallet().PublicKey(), instrs: []solana.Instruction{memo("a")}, wantErr: ErrMissingSignatures},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := rpcServer(t, map[string]interface{}{
+				"getAccountInfo": map[string]interface{}{
+					"context": map[string]interface{}{"slot": 1},
+					"value":   nonceAccountData(nonce, tt.authority),
+				},
+			})
+			defer server.Close()
+
+			tx, err := BuildNonceTransacion(context.Background(), rpc.New(server.URL), []Signer{NewPrivateKeySigner(owner)}, nil, nonceAccount, tt.instrs...)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tx.Message.RecentBlockhash != nonce {
+				t.Errorf("blockhash %v, want the nonce %v", tx.Message.RecentBlockhash, nonce)
+			}
+			if account, ok := NonceAccountOf(tx); !ok || !account.Equals(nonceAccount) {
+				t.Fatalf("first instruction advances %v %v, want %v", account, ok, nonceAccount)
+			}
+			advance := tx.Message.Instructions[0]
+			authority := tx.Message.AccountKeys[advance.Accounts[2]]
+			if !authority.Equals(tt.authority) {
+				t.Errorf("advance authority %v, want %v", authority, tt.authority)
+			}
+
+			if len(tx.Message.Instructions) != len(tt.instrs)+1 {
+				t.Fatalf("%v instructions, want %v", len(tx.Message.Instructions), len(tt.instrs)+1)
+			}
+			for i, inst := range tt.instrs {
+				compiled := tx.Message.Instructions[i+1]
+				program, err := tx.Message.Program(compiled.ProgramIDIndex)
+				if err != nil {
+					t.Fatal(err)
+				}
+				data, _ := inst.Data()
+				if !program.Equals(inst.ProgramID()) || string(compiled.Data) != string(data) {
+					t.Errorf("instruction %v: %v %x, want %v %x", i+1, program, []byte(compiled.Data), inst.ProgramID(), data)
+				}
+			}
+			if err := tx.VerifySignatures(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestNonceAccountOf(t *testing.T) {
+	payer := solana.NewWallet().PublicKey()
+	nonceAccount := solana.NewWallet().PublicKey()
+	memo := solana.NewInstruction(solana.MemoProgramID, solana.AccountMetaSlice{}, []byte("a"))
+	advance := system.NewAdvanceNonceAccountInstruction(nonceAccount, solana.SysVarRecentBlockHashesPubkey, payer).Build()
+
+	tests := []struct {
+		name   string
+		instrs []solana.Instruction
+		want   bool
+	}{
+		{name: "advance first", instrs: []solana.Instruction{advance, memo}, want: true},
+		{name: "recent blockhash", instrs: []solana.Instruction{memo}},
+		{name: "advance after compute budget", instrs: []solana.Instruction{computebudget.NewSetComputeUnitLimitInstruction(1).Build(), advance, memo}},
+		{name: "other system instruction", instrs: []solana.Instruction{system.NewTransferInstruction(1, payer, nonceAccount).Build(), memo}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, err := solana.NewTransaction(tt.instrs, solana.Hash{1}, solana.TransactionPayer(payer))
+			if err != nil {
+				t.Fatal(err)
+			}
+			account, ok := NonceAccountOf(tx)
+			if ok != tt.want {
+				t.Fatalf("NonceAccountOf = %v, want %v", ok, tt.want)
+			}
+			if ok && !account.Equals(nonceAccount) {
+				t.Fatalf("nonce account %v, want %v", account, nonceAccount)
+			}
+		})
+	}
+}
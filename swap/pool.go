@@ -14,7 +14,7 @@ import (
 )
 
 // GetPool x
-func GetPool(clientRPC *rpc.Client, fromToken string, toToken string) (models.PoolConfig, bool) {
+func GetPool(ctx context.Context, clientRPC *rpc.Client, fromToken string, toToken string) (models.PoolConfig, bool) {
 	poolDb1 := models.GetPoolConfig(fromToken, toToken)
 	if poolDb1.BaseMint == fromToken && poolDb1.QuoteMint == toToken {
 		return poolDb1, false
@@ -27,14 +27,14 @@ func GetPool(clientRPC *rpc.Client, fromToken string, toToken string) (models.Po
 
 	pools := []models.PoolConfig{}
 
-	pools1, err := getPools(clientRPC, fromToken, toToken)
+	pools1, err := getPools(ctx, clientRPC, fromToken, toToken)
 	if err != nil {
 		log.Printf("GetPool err: %v", err)
 	} else {
 		pools = append(pools, pools1...)
 	}
 
-	pools2, err := getPools(clientRPC, toToken, fromToken)
+	pools2, err := getPools(ctx, clientRPC, toToken, fromToken)
 	if err != nil {
 		log.Printf("GetPool err: %v", err)
 	} else {
@@ -49,7 +49,7 @@ func GetPool(clientRPC *rpc.Client, fromToken string, toToken string) (models.Po
 	log.Printf("pools: %v", pools)
 	if len(pools) > 1 {
 		for _, pool := range pools {
-			ba, qa, err := getPoolAmounts(clientRPC, pool)
+			ba, qa, err := getPoolAmounts(ctx, clientRPC, pool)
 			if err != nil {
 				log.Printf("GetPool err: %v", err)
 			} else {
@@ -95,8 +95,8 @@ func GetPool(clientRPC *rpc.Client, fromToken string, toToken string) (models.Po
 }
 
 // GetPool x
-func getPoolAmounts(clientRPC *rpc.Client, pool models.PoolConfig) (uint64, uint64, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+func getPoolAmounts(ctx context.Context, clientRPC *rpc.Client, pool models.PoolConfig) (uint64, uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
 	defer cancel()
 
 	res, err := clientRPC.GetMultipleAccounts(
@@ -127,8 +127,8 @@ func getPoolAmounts(clientRPC *rpc.Client, pool models.PoolConfig) (uint64, uint
 }
 
 // GetPool x
-func getPools(clientRPC *rpc.Client, fromToken string, toToken string) ([]models.PoolConfig, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+func getPools(ctx context.Context, clientRPC *rpc.Client, fromToken string, toToken string) ([]models.PoolConfig, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
 	defer cancel()
 
 	pools := []models.PoolConfig{}
@@ -160,44 +160,65 @@ func getPools(clientRPC *rpc.Client, fromToken string, toToken string) ([]models
 		return pools, err
 	}
 
+	infos := []*RaydiumV4{}
+	ids := []solana.PublicKey{}
 	for _, res := range resp {
 		info := &RaydiumV4{}
 
 		if err := info.Decode(res.Account.Data.GetBinary()); err != nil {
-			log.Printf("decoding RaydiumV4: %w", err)
+			log.Printf("decoding RaydiumV4: %v", err)
 		} else {
-			mres, err := clientRPC.GetAccountInfo(ctx, info.MarketID)
-			if err != nil {
-				log.Printf("GetPool err: %v", err)
-			} else {
-				if mres != nil {
-					if mres.Value != nil {
-						market := &MarketV3{}
-						if err := market.Decode(mres.Value.Data.GetBinary()); err != nil {
-							log.Printf("decoding MarketV3: %w", err)
-						} else {
-							pool := models.PoolConfig{
-								ID:               res.Pubkey.String(),
-								BaseMint:         info.BaseMint.String(),
-								QuoteMint:        info.QuoteMint.String(),
-								BaseDecimals:     int(info.BaseDecimal),
-								QuoteDecimals:    int(info.QuoteDecimal),
-								OpenOrders:       info.OpenOrders.String(),
-								TargetOrders:     info.TargetOrders.String(),
-								BaseVault:        info.BaseVault.String(),
-								QuoteVault:       info.QuoteVault.String(),
-								MarketID:         info.MarketID.String(),
-								MarketBaseVault:  market.BaseVault.String(),
-								MarketQuoteVault: market.QuoteVault.String(),
-								MarketBids:       market.Bids.String(),
-								MarketAsks:       market.Asks.String(),
-								MarketEventQueue: market.EventQueue.String(),
-							}
-							pools = append(pools, pool)
-						}
-					}
-				}
+			infos = append(infos, info)
+			ids = append(ids, res.Pubkey)
+		}
+	}
+
+	// Fetch all markets at once instead of one GetAccountInfo per pool
+	for start := 0; start < len(infos); start += 100 {
+		end := start + 100
+		if end > len(infos) {
+			end = len(infos)
+		}
+
+		marketIDs := []solana.PublicKey{}
+		for _, info := range infos[start:end] {
+			marketIDs = append(marketIDs, info.MarketID)
+		}
+
+		mres, err := clientRPC.GetMultipleAccounts(ctx, marketIDs...)
+		if err != nil {
+			log.Printf("GetPool err: %v", err)
+			continue
+		}
+
+		for i, m := range mres.Value {
+			if m == nil {
+				continue
+			}
+			info := infos[start+i]
+			market := &MarketV3{}
+			if err := market.Decode(m.Data.GetBinary()); err != nil {
+				log.Printf("decoding MarketV3: %v", err)
+				continue
+			}
+			pool := models.PoolConfig{
+				ID:               ids[start+i].String(),
+				BaseMint:         info.BaseMint.String(),
+				QuoteMint:        info.QuoteMint.String(),
+				BaseDecimals:     int(info.BaseDecimal),
+				QuoteDecimals:    int(info.QuoteDecimal),
+				OpenOrders:       info.OpenOrders.String(),
+				TargetOrders:     info.TargetOrders.String(),
+				BaseVault:        info.BaseVault.String(),
+				QuoteVault:       info.QuoteVault.String(),
+				MarketID:         info.MarketID.String(),
+				MarketBaseVault:  market.BaseVault.String(),
+				MarketQuoteVault: market.QuoteVault.String(),
+				MarketBids:       market.Bids.String(),
+				MarketAsks:       market.Asks.String(),
+				MarketEventQueue: market.EventQueue.String(),
 			}
+			pools = append(pools, pool)
 		}
 	}
 	return pools, nil
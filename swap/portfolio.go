@@ -0,0 +1,275 @@
+package swap
+
+import (
+	"context"
+	"encoding/binary"
+	"strings"
+	"time"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+	"main/models"
+)
+
+// USDCMint x
+var USDCMint = solana.MustPublicKeyFromBase58("EPjFWdd5AufqSSqeM8mhSsLNgsTdy1tyRWxy8Fqxt7mk")
+
+// TokenMetadataProgramID Metaplex token metadata
+var TokenMetadataProgramID = solana.MustPublicKeyFromBase58("metaqbxxUerdq28cj1RbAWkYQm3ybzjb6a8bt518x1s")
+
+// maxMultipleAccounts keys per GetMultipleAccounts call
+const maxMultipleAccounts = 100
+
+// knownSymbols used when a mint has no metadata account
+var knownSymbols = map[solana.PublicKey]string{
+	solana.SolMint: "SOL",
+	USDCMint:       "USDC",
+	solana.MustPublicKeyFromBase58("Es9vMFrzaCERmJfrF4H2FYD4KCoNkY11McCe8BenwNYB"): "USDT",
+}
+
+// Holding balance of one mint with its value
+type Holding struct {
+	Mint     solana.PublicKey `json:"mint"`
+	Symbol   string           `json:"symbol"`
+	Decimals int              `json:"decimals"`
+	Amount   uint64           `json:"amount"`
+	UIAmount float64          `json:"uiAmount"`
+	// Priced is false when no known pool values the mint
+	Priced    bool    `json:"priced"`
+	ValueSOL  float64 `json:"valueSol"`
+	ValueUSDC float64 `json:"valueUsdc"`
+}
+
+// Portfolio holdings of an owner valued in SOL and USDC
+type Portfolio struct {
+	Owner     solana.PublicKey `json:"owner"`
+	Holdings  []Holding        `json:"holdings"`
+	TotalSOL  float64          `json:"totalSol"`
+	TotalUSDC float64          `json:"totalUsdc"`
+	// SOLPrice in USDC, zero without a known SOL/USDC pool
+	SOLPrice float64   `json:"solPrice"`
+	Time     time.Time `json:"time"`
+}
+
+// GetPortfolio lists the SOL and token balances of owner, valued with the vault
+// reserves of Raydium pools stored in models
+func GetPortfolio(ctx context.Context, clientRPC *rpc.Client, owner solana.PublicKey) (*Portfolio, error) {
+	balanceCtx, cancel := context.WithTimeout(ctx, time.Second*20)
+	balance, err := clientRPC.GetBalance(balanceCtx, owner, rpc.CommitmentConfirmed)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := GetOwnerTokenAccounts(ctx, clientRPC, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	// Several accounts may hold the same mint, wrapped SOL adds to the SOL balance
+	amounts := map[solana.PublicKey]uint64{solana.SolMint: balance.Value}
+	mints := []solana.PublicKey{solana.SolMint}
+	for _, a := range accounts {
+		if a.Amount == 0 {
+			continue
+		}
+		if _, ok := amounts[a.Mint]; !ok {
+			mints = append(mints, a.Mint)
+		}
+		amounts[a.Mint] += a.Amount
+	}
+
+	decimals, err := getMintDecimals(ctx, clientRPC, mints...)
+	if err != nil {
+		return nil, err
+	}
+	symbols, err := getMintSymbols(ctx, clientRPC, mints...)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Portfolio{
+		Owner: owner,
+		Time:  time.Now(),
+	}
+	p.SOLPrice, _ = poolPrice(ctx, clientRPC, solana.SolMint, USDCMint)
+
+	for _, mint := range mints {
+		h := Holding{
+			Mint:     mint,
+			Symbol:   symbols[mint],
+			Decimals: decimals[mint],
+			Amount:   amounts[mint],
+		}
+		h.UIAmount = ToFloat(h.Amount, h.Decimals)
+
+		switch {
+		case mint.Equals(solana.SolMint):
+			h.Priced = true
+			h.ValueSOL = h.UIAmount
+			h.ValueUSDC = h.UIAmount * p.SOLPrice
+		case mint.Equals(USDCMint):
+			h.Priced = p.SOLPrice > 0
+			h.ValueUSDC = h.UIAmount
+			if h.Priced {
+				h.ValueSOL = h.UIAmount / p.SOLPrice
+			}
+		default:
+			if price, ok := poolPrice(ctx, clientRPC, mint, solana.SolMint); ok {
+				h.Priced = true
+				h.ValueSOL = h.UIAmount * price
+				h.ValueUSDC = h.ValueSOL * p.SOLPrice
+			} else if price, ok := poolPrice(ctx, clientRPC, mint, USDCMint); ok && p.SOLPrice > 0 {
+				h.Priced = true
+				h.ValueUSDC = h.UIAmount * price
+				h.ValueSOL = h.ValueUSDC / p.SOLPrice
+			}
+		}
+
+		p.TotalSOL += h.ValueSOL
+		p.TotalUSDC += h.ValueUSDC
+		p.Holdings = append(p.Holdings, h)
+	}
+
+	return p, nil
+}
+
+// poolPrice price of one base token in quote from a stored pool of the pair
+func poolPrice(ctx context.Context, clientRPC *rpc.Client, base solana.PublicKey, quote solana.PublicKey) (float64, bool) {
+	pool := models.GetPoolConfig(base.String(), quote.String())
+	reverse := false
+	if pool.ID == "" {
+		pool = models.GetPoolConfig(quote.String(), base.String())
+		reverse = true
+	}
+	if pool.ID == "" {
+		return 0, false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	baseAmount, quoteAmount, err := getPoolAmounts(ctx, clientRPC, pool)
+	if err != nil || baseAmount == 0 || quoteAmount == 0 {
+		return 0, false
+	}
+
+	b := ToFloat(baseAmount, pool.BaseDecimals)
+	q := ToFloat(quoteAmount, pool.QuoteDecimals)
+	if reverse {
+		return b / q, true
+	}
+	return q / b, true
+}
+
+// getMintDecimals decimals of mints of the Token and Token-2022 programs
+func getMintDecimals(ctx context.Context, clientRPC *rpc.Client, mints ...solana.PublicKey) (map[solana.PublicKey]int, error) {
+	res := map[solana.PublicKey]int{solana.SolMint: 9}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	accounts, err := getAccounts(ctx, clientRPC, mints...)
+	if err != nil {
+		return nil, err
+	}
+	for i, a := range accounts {
+		if a == nil {
+			continue
+		}
+		// Token-2022 extensions follow the base mint layout
+		var mint token.Mint
+		err = bin.NewBinDecoder(a.Data.GetBinary()).Decode(&mint)
+		if err != nil {
+			return nil, err
+		}
+		res[mints[i]] = int(mint.Decimals)
+	}
+	return res, nil
+}
+
+// getMintSymbols symbols from Metaplex metadata, known symbols or a short mint otherwise
+func getMintSymbols(ctx context.Context, clientRPC *rpc.Client, mints ...solana.PublicKey) (map[solana.PublicKey]string, error) {
+	res := map[solana.PublicKey]string{}
+	metadata := []solana.PublicKey{}
+	for _, mint := range mints {
+		address, _, err := solana.FindProgramAddress(
+			[][]byte{[]byte("metadata"), TokenMetadataProgramID.Bytes(), mint.Bytes()},
+			TokenMetadataProgramID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		metadata = append(metadata, address)
+
+		if symbol, ok := knownSymbols[mint]; ok {
+			res[mint] = symbol
+		} else {
+			res[mint] = mint.Short(4)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	accounts, err := getAccounts(ctx, clientRPC, metadata...)
+	if err != nil {
+		return nil, err
+	}
+	for i, a := range accounts {
+		if a == nil {
+			continue
+		}
+		if _, ok := knownSymbols[mints[i]]; ok {
+			continue
+		}
+		if symbol := metadataSymbol(a.Data.GetBinary()); symbol != "" {
+			res[mints[i]] = symbol
+		}
+	}
+	return res, nil
+}
+
+// getAccounts GetMultipleAccounts in chunks of the 100 keys rpc nodes accept per call
+func getAccounts(ctx context.Context, clientRPC *rpc.Client, keys ...solana.PublicKey) ([]*rpc.Account, error) {
+	accounts := []*rpc.Account{}
+	for start := 0; start < len(keys); start += maxMultipleAccounts {
+		end := start + maxMultipleAccounts
+		if end > len(keys) {
+			end = len(keys)
+		}
+		res, err := clientRPC.GetMultipleAccounts(ctx, keys[start:end]...)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, res.Value...)
+	}
+	return accounts, nil
+}
+
+// metadataSymbol reads the symbol of a Metaplex metadata account:
+// key u8, update authority, mint, then borsh name and symbol strings
+func metadataSymbol(data []byte) string {
+	offset := 1 + 32 + 32
+	readString := func() (string, bool) {
+		if len(data) < offset+4 {
+			return "", false
+		}
+		size := int(binary.LittleEndian.Uint32(data[offset:]))
+		offset += 4
+		if size > len(data)-offset {
+			return "", false
+		}
+		s := string(data[offset : offset+size])
+		offset += size
+		return strings.TrimRight(s, "\x00 "), true
+	}
+
+	if _, ok := readString(); !ok {
+		return ""
+	}
+	symbol, _ := readString()
+	return symbol
+}
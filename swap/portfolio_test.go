@@ -0,0 +1,94 @@
+package swap
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"main/models"
+)
+
+// metadata Metaplex metadata account data with name and symbol padded to size like on chain
+func metadata(name string, symbol string, size int) []byte {
+	data := make([]byte, 1+32+32)
+	for _, s := range []string{name, symbol} {
+		padded := make([]byte, size)
+		copy(padded, s)
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(padded)))
+		data = append(data, padded...)
+	}
+	return data
+}
+
+func TestMetadataSymbol(t *testing.T) {
+	oversized := metadata("Token", "TKN", 10)
+	binary.LittleEndian.PutUint32(oversized[1+32+32+4+10:], 1000)
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{name: "padded", data: metadata("Bonk", "BONK", 10), want: "BONK"},
+		{name: "exact size", data: metadata("Wrapped", "WETH", 4), want: "WETH"},
+		{name: "trailing spaces", data: metadata("Token", "TKN   ", 6), want: "TKN"},
+		{name: "empty symbol", data: metadata("Token", "", 10), want: ""},
+		{name: "too short", data: make([]byte, 40), want: ""},
+		{name: "name past the end", data: metadata("Token", "TKN", 10)[:1+32+32+4+5], want: ""},
+		{name: "symbol size past the end", data: oversized, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := metadataSymbol(tt.data); got != tt.want {
+				t.Fatalf("metadataSymbol = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPoolPrice(t *testing.T) {
+	if err := models.Init(models.Config{DSN: filepath.Join(t.TempDir(), "test.db")}); err != nil {
+		t.Fatal(err)
+	}
+	defer models.Close()
+
+	mint := solana.NewWallet().PublicKey()
+	if err := testPool(mint, solana.SolMint).Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Vaults hold 2 tokens of 6 decimals against 5 SOL
+	tests := []struct {
+		name   string
+		base   solana.PublicKey
+		quote  solana.PublicKey
+		vaults []uint64
+		want   float64
+		wantOk bool
+	}{
+		{name: "stored pair", base: mint, quote: solana.SolMint, vaults: []uint64{2000000, 5000000000}, want: 2.5, wantOk: true},
+		{name: "reversed pair", base: solana.SolMint, quote: mint, vaults: []uint64{2000000, 5000000000}, want: 0.4, wantOk: true},
+		{name: "no pool", base: mint, quote: USDCMint, vaults: []uint64{2000000, 5000000000}},
+		{name: "empty vault", base: mint, quote: solana.SolMint, vaults: []uint64{0, 5000000000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := vaultServer(t, tt.vaults...)
+			defer server.Close()
+
+			price, ok := poolPrice(context.Background(), rpc.New(server.URL), tt.base, tt.quote)
+			if ok != tt.wantOk {
+				t.Fatalf("poolPrice ok = %v, want %v", ok, tt.wantOk)
+			}
+			if math.Abs(price-tt.want) > 1e-9 {
+				t.Fatalf("poolPrice = %v, want %v", price, tt.want)
+			}
+		})
+	}
+}
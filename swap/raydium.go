@@ -7,7 +7,6 @@ import (
 	"fmt"
 	bin "github.com/gagliardetto/binary"
 	"github.com/gagliardetto/solana-go"
-	associatedtokenaccount "github.com/gagliardetto/solana-go/programs/associated-token-account"
 	"github.com/gagliardetto/solana-go/programs/system"
 	"github.com/gagliardetto/solana-go/programs/token"
 	"github.com/gagliardetto/solana-go/rpc"
@@ -30,37 +29,165 @@ func FromFloat(v float64, decimals int) uint64 {
 // RaydiumSwap x
 type RaydiumSwap struct {
 	clientRPC *rpc.Client
-	account   solana.PrivateKey
+	owner     solana.PublicKey
+	// signer is nil when only the owner public key is known
+	signer Signer
+	// feePayer pays fees and rent instead of the owner when set
+	feePayer    Signer
+	priorityFee PriorityFeeConfig
+	execute     ExecuteConfig
+	wrap        WrapConfig
 }
 
 // Swap x
 func (s *RaydiumSwap) Swap(
+	ctx context.Context,
 	pool *models.PoolConfig,
 	amount uint64,
 	minOutAmount uint64,
 	fromAccount solana.PublicKey,
 	toAccount solana.PublicKey,
 	reverse bool,
-	isMissingFrom bool,
-	isMissingTo bool,
-) (*solana.Signature, error) {
+	missingMints []solana.PublicKey,
+) (*Confirmation, error) {
 
-	log.Printf("isMissingFrom: %v %v", isMissingFrom, fromAccount)
-	log.Printf("isMissingTo: %v %v", isMissingTo, toAccount)
+	if s.signer == nil {
+		return nil, ErrNoPrivateKey
+	}
+
+	instrs, signers, err := s.Instructions(ctx, pool, amount, minOutAmount, fromAccount, toAccount, reverse, missingMints)
+	if err != nil {
+		return nil, err
+	}
+	signers = s.signers(signers)
+
+	conf, err := ExecuteInstructionsAndWait(ctx, s.clientRPC, signers, s.executeConfig(pool), instrs...)
+	if err != nil {
+		return conf, err
+	}
+
+	return conf, nil
+}
 
-	if isMissingFrom == true && isMissingTo == true {
-		return nil, fmt.Errorf("isMissingFrom and isMissingTo")
+// Build returns the swap transaction signed only by generated accounts and the fee payer,
+// the owner signs it externally
+func (s *RaydiumSwap) Build(
+	ctx context.Context,
+	pool *models.PoolConfig,
+	amount uint64,
+	minOutAmount uint64,
+	fromAccount solana.PublicKey,
+	toAccount solana.PublicKey,
+	reverse bool,
+	missingMints []solana.PublicKey,
+) (*solana.Transaction, uint64, error) {
+
+	instrs, signers, err := s.Instructions(ctx, pool, amount, minOutAmount, fromAccount, toAccount, reverse, missingMints)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	instrs := []solana.Instruction{}
-	signers := []solana.PrivateKey{s.account}
+	cfg := s.executeConfig(pool)
+	tables, err := GetAddressTables(ctx, s.clientRPC, cfg.LookupTables...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// The owner key is left out even when loaded, its signature is added by the external signer
+	if s.feePayer != nil {
+		signers = append([]Signer{s.feePayer}, signers...)
+	}
+	return BuildUnsignedTransacion(ctx, s.clientRPC, s.payer(), signers, tables, cfg, instrs...)
+}
+
+// payer of fees and rent, the owner without a fee payer
+func (s *RaydiumSwap) payer() solana.PublicKey {
+	if s.feePayer != nil {
+		return s.feePayer.PublicKey()
+	}
+	return s.owner
+}
+
+// signers of the swap with the fee payer first so it pays the transaction fee
+func (s *RaydiumSwap) signers(extra []Signer) []Signer {
+	signers := []Signer{}
+	if s.feePayer != nil {
+		signers = append(signers, s.feePayer)
+	}
+	if s.signer != nil {
+		signers = append(signers, s.signer)
+	}
+	return append(signers, extra...)
+}
+
+func (s *RaydiumSwap) executeConfig(pool *models.PoolConfig) ExecuteConfig {
+	cfg := s.execute
+	if pool.LookupTable != "" {
+		cfg.LookupTables = append([]solana.PublicKey{solana.MustPublicKeyFromBase58(pool.LookupTable)}, cfg.LookupTables...)
+	}
+	return cfg
+}
+
+// Instructions builds the swap instructions and the generated accounts that must sign them
+func (s *RaydiumSwap) Instructions(
+	ctx context.Context,
+	pool *models.PoolConfig,
+	amount uint64,
+	minOutAmount uint64,
+	fromAccount solana.PublicKey,
+	toAccount solana.PublicKey,
+	reverse bool,
+	missingMints []solana.PublicKey,
+) ([]solana.Instruction, []Signer, error) {
+
+	log.Printf("from: %v to: %v missing: %v", fromAccount, toAccount, missingMints)
+
+	instrs, err := NewComputeBudgetInstructions(ctx, s.clientRPC, s.priorityFee, PoolWritableAccounts(pool)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	signers := []Signer{}
 	tempAccount := solana.NewWallet()
+	payer := s.payer()
+	var payerRent uint64
 
 	needWrapSOL := pool.BaseMint == "So11111111111111111111111111111111111111112" || pool.QuoteMint == "So11111111111111111111111111111111111111112"
 	log.Printf("need wrap0: %v", needWrapSOL)
 
-	if needWrapSOL {
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second*20)
+	useATA := needWrapSOL && (s.wrap.UseATA || s.wrap.KeepWrapped)
+	wrappedAccount := tempAccount.PublicKey()
+
+	if useATA {
+		spendSOL := (reverse == false && pool.BaseMint == "So11111111111111111111111111111111111111112") ||
+			(reverse == true && pool.QuoteMint == "So11111111111111111111111111111111111111112")
+
+		var lamports uint64
+		if spendSOL {
+			lamports = amount
+		}
+
+		wrapInstrs, ata, err := NewWrapSOLInstructions(ctx, s.clientRPC, payer, s.owner, lamports)
+		if err != nil {
+			return nil, nil, err
+		}
+		instrs = append(instrs, wrapInstrs...)
+		wrappedAccount = ata
+
+		if !payer.Equals(s.owner) && !s.wrap.KeepWrapped {
+			// The fee payer funds the account only when it is created here
+			payerRent, err = newAccountRent(ctx, s.clientRPC, ata)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		if spendSOL {
+			fromAccount = ata
+		} else {
+			toAccount = ata
+		}
+	} else if needWrapSOL {
+		ctx, cancel := context.WithTimeout(ctx, time.Second*20)
 		defer cancel()
 
 		log.Printf("need wrap1")
@@ -71,46 +198,67 @@ func (s *RaydiumSwap) Swap(
 		)
 
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
-		accountLamports := rentCost
+		spendLamports := uint64(0)
 		if reverse == false {
 			if pool.BaseMint == "So11111111111111111111111111111111111111112" {
-				accountLamports += amount
+				spendLamports = amount
 			}
 		} else {
 			if pool.QuoteMint == "So11111111111111111111111111111111111111112" {
-				accountLamports += amount
+				spendLamports = amount
 			}
 		}
 
+		// A separate fee payer funds only the rent, the swapped SOL always comes from the owner
+		accountLamports := rentCost + spendLamports
+		if !payer.Equals(s.owner) {
+			accountLamports = rentCost
+			payerRent = rentCost
+		}
+
 		createInst, err := system.NewCreateAccountInstruction(
 			accountLamports,
 			165,
 			solana.TokenProgramID,
-			s.account.PublicKey(),
+			payer,
 			tempAccount.PublicKey(),
 		).ValidateAndBuild()
 
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		instrs = append(instrs, createInst)
+
+		if accountLamports < rentCost+spendLamports {
+			// Native token accounts take their amount from the lamports present at initialization
+			transferInst, err := system.NewTransferInstruction(
+				spendLamports,
+				s.owner,
+				tempAccount.PublicKey(),
+			).ValidateAndBuild()
+			if err != nil {
+				return nil, nil, err
+			}
+			instrs = append(instrs, transferInst)
+		}
+
 		initInst, err := token.NewInitializeAccountInstruction(
 			tempAccount.PublicKey(),
 			solana.MustPublicKeyFromBase58("So11111111111111111111111111111111111111112"),
-			s.account.PublicKey(),
+			s.owner,
 			solana.SysVarRentPubkey,
 		).ValidateAndBuild()
 
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		instrs = append(instrs, initInst)
-		signers = append(signers, tempAccount.PrivateKey)
+		signers = append(signers, NewPrivateKeySigner(tempAccount.PrivateKey))
 
 		if reverse == false {
 			// Use this new temp account as from or to
@@ -131,20 +279,20 @@ func (s *RaydiumSwap) Swap(
 		}
 	}
 
-	if isMissingFrom == true || isMissingTo == true {
-		mint := pool.BaseMint
-		if pool.BaseMint == "So11111111111111111111111111111111111111112" {
-			mint = pool.QuoteMint
+	for _, mint := range missingMints {
+		// Native SOL needs no token account, it is wrapped through the temp account
+		if mint.Equals(solana.SystemProgramID) || mint.Equals(solana.SolMint) {
+			continue
 		}
 
 		log.Printf("need to create token account: %v", mint)
-		inst, err := associatedtokenaccount.NewCreateInstruction(
-			s.account.PublicKey(),
-			s.account.PublicKey(),
-			solana.MustPublicKeyFromBase58(mint),
-		).ValidateAndBuild()
+		inst, err := NewCreateIdempotentATAInstruction(
+			payer,
+			s.owner,
+			mint,
+		)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		instrs = append(instrs, inst)
 	}
@@ -155,30 +303,65 @@ func (s *RaydiumSwap) Swap(
 		pool,
 		fromAccount,
 		toAccount,
-		s.account.PublicKey(),
+		s.owner,
 	))
 
-	if needWrapSOL {
+	if needWrapSOL && !s.wrap.KeepWrapped {
 		log.Printf("need wrap2")
 		closeInst, err := token.NewCloseAccountInstruction(
-			tempAccount.PublicKey(),
-			s.account.PublicKey(),
-			s.account.PublicKey(),
+			wrappedAccount,
+			s.owner,
+			s.owner,
 			[]solana.PublicKey{},
 		).ValidateAndBuild()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		instrs = append(instrs, closeInst)
+
+		if payerRent > 0 {
+			// Closing paid the rent funded by the fee payer to the owner, return it
+			refundInst, err := system.NewTransferInstruction(
+				payerRent,
+				s.owner,
+				payer,
+			).ValidateAndBuild()
+			if err != nil {
+				return nil, nil, err
+			}
+			instrs = append(instrs, refundInst)
+		}
 	}
 	log.Printf("execute: %#v", instrs)
 
-	sig, err := ExecuteInstructionsAndWait(s.clientRPC, signers, instrs...)
+	return instrs, signers, nil
+}
+
+// NewCreateIdempotentATAInstruction creates the associated token account of owner for mint,
+// succeeding when the account already exists
+func NewCreateIdempotentATAInstruction(
+	payer solana.PublicKey,
+	owner solana.PublicKey,
+	mint solana.PublicKey,
+) (solana.Instruction, error) {
+	ata, _, err := solana.FindAssociatedTokenAddress(owner, mint)
 	if err != nil {
 		return nil, err
 	}
 
-	return sig, nil
+	return solana.NewInstruction(
+		solana.SPLAssociatedTokenAccountProgramID,
+		solana.AccountMetaSlice{
+			solana.Meta(payer).WRITE().SIGNER(),
+			solana.Meta(ata).WRITE(),
+			solana.Meta(owner),
+			solana.Meta(mint),
+			solana.Meta(solana.SystemProgramID),
+			solana.Meta(solana.TokenProgramID),
+		},
+		// CreateIdempotent instruction is number 1
+		[]byte{1},
+	), nil
 }
 
 // RaySwapInstruction x
@@ -191,7 +374,7 @@ type RaySwapInstruction struct {
 
 // ProgramID x
 func (inst *RaySwapInstruction) ProgramID() solana.PublicKey {
-	return solana.MustPublicKeyFromBase58("675kPX9MHTjS2zt1qfr1NYHuzeLXfQM9H24wFSUt1Mp8")
+	return RaydiumLiquidityPoolV4ProgramID
 }
 
 // Accounts x
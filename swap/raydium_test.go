@@ -0,0 +1,228 @@
+package swap
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+	"main/models"
+)
+
+// testPool pool of baseMint and quoteMint with random accounts
+func testPool(baseMint solana.PublicKey, quoteMint solana.PublicKey) *models.PoolConfig {
+	key := func() string { return solana.NewWallet().PublicKey().String() }
+	return &models.PoolConfig{
+		ID:               key(),
+		BaseMint:         baseMint.String(),
+		QuoteMint:        quoteMint.String(),
+		BaseDecimals:     6,
+		QuoteDecimals:    9,
+		OpenOrders:       key(),
+		TargetOrders:     key(),
+		BaseVault:        key(),
+		QuoteVault:       key(),
+		MarketID:         key(),
+		MarketBaseVault:  key(),
+		MarketQuoteVault: key(),
+		MarketBids:       key(),
+		MarketAsks:       key(),
+		MarketEventQueue: key(),
+	}
+}
+
+func TestBuildLeavesOwnerUnsigned(t *testing.T) {
+	server := rpcServer(t, map[string]interface{}{
+		"getLatestBlockhash": map[string]interface{}{
+			"context": map[string]interface{}{"slot": 1},
+			"value": map[string]interface{}{
+				"blockhash":            solana.Hash{1}.String(),
+				"lastValidBlockHeight": 100,
+			},
+		},
+	})
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		feePayer bool
+	}{
+		{name: "owner pays"},
+		{name: "separate fee payer", feePayer: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owner := solana.NewWallet().PrivateKey
+			s := &RaydiumSwap{
+				clientRPC: rpc.New(server.URL),
+				owner:     owner.PublicKey(),
+				signer:    NewPrivateKeySigner(owner),
+			}
+			if tt.feePayer {
+				s.feePayer = NewPrivateKeySigner(solana.NewWallet().PrivateKey)
+			}
+
+			pool := testPool(solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey())
+			tx, _, err := s.Build(context.Background(), pool, 1000, 900, solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), false, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			missing := MissingSigners(tx)
+			if len(missing) != 1 || !missing[0].Equals(owner.PublicKey()) {
+				t.Fatalf("missing signers = %v, want only the owner %v", missing, owner.PublicKey())
+			}
+		})
+	}
+}
+
+func TestWrapRentRefund(t *testing.T) {
+	const rent = 2039280
+
+	tests := []struct {
+		name        string
+		feePayer    bool
+		ataExists   bool
+		keepWrapped bool
+		wantRefund  bool
+	}{
+		{name: "fee payer creates the account", feePayer: true, wantRefund: true},
+		{name: "account already exists", feePayer: true, ataExists: true},
+		{name: "account kept", feePayer: true, keepWrapped: true},
+		{name: "owner pays"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ata interface{}
+			if tt.ataExists {
+				ata = tokenAccount(0)
+			}
+			server := rpcServer(t, map[string]interface{}{
+				"getMultipleAccounts": map[string]interface{}{
+					"context": map[string]interface{}{"slot": 1},
+					"value":   []interface{}{ata},
+				},
+				"getMinimumBalanceForRentExemption": rent,
+			})
+			defer server.Close()
+
+			owner := solana.NewWallet().PrivateKey
+			s := &RaydiumSwap{
+				clientRPC: rpc.New(server.URL),
+				owner:     owner.PublicKey(),
+				signer:    NewPrivateKeySigner(owner),
+				wrap:      WrapConfig{UseATA: true, KeepWrapped: tt.keepWrapped},
+			}
+			payer := owner.PublicKey()
+			if tt.feePayer {
+				feePayer := solana.NewWallet().PrivateKey
+				s.feePayer = NewPrivateKeySigner(feePayer)
+				payer = feePayer.PublicKey()
+			}
+
+			// Buying SOL, the wSOL account receives the output
+			pool := testPool(solana.NewWallet().PublicKey(), solana.SolMint)
+			instrs, _, err := s.Instructions(context.Background(), pool, 1000, 900, solana.NewWallet().PublicKey(), solana.PublicKey{}, false, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			refunded := uint64(0)
+			for _, inst := range instrs {
+				if !inst.ProgramID().Equals(solana.SystemProgramID) {
+					continue
+				}
+				data, err := inst.Data()
+				if err != nil {
+					t.Fatal(err)
+				}
+				decoded, err := system.DecodeInstruction(inst.Accounts(), data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				transfer, ok := decoded.Impl.(*system.Transfer)
+				if ok && transfer.GetRecipientAccount().PublicKey.Equals(payer) {
+					refunded += *transfer.Lamports
+				}
+			}
+
+			want := uint64(0)
+			if tt.wantRefund {
+				want = rent
+			}
+			if refunded != want {
+				t.Fatalf("refunded %v to the fee payer, want %v", refunded, want)
+			}
+		})
+	}
+}
+
+func TestCreateMissingAccounts(t *testing.T) {
+	baseMint := solana.NewWallet().PublicKey()
+	quoteMint := solana.NewWallet().PublicKey()
+
+	tests := []struct {
+		name     string
+		missing  []solana.PublicKey
+		feePayer bool
+		want     []solana.PublicKey
+	}{
+		{name: "none missing"},
+		{name: "destination missing", missing: []solana.PublicKey{quoteMint}, want: []solana.PublicKey{quoteMint}},
+		{name: "both missing", missing: []solana.PublicKey{baseMint, quoteMint}, want: []solana.PublicKey{baseMint, quoteMint}},
+		{name: "fee payer funds accounts", missing: []solana.PublicKey{baseMint, quoteMint}, feePayer: true, want: []solana.PublicKey{baseMint, quoteMint}},
+		{name: "native SOL skipped", missing: []solana.PublicKey{solana.SolMint, solana.SystemProgramID, quoteMint}, want: []solana.PublicKey{quoteMint}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owner := solana.NewWallet().PrivateKey
+			s := &RaydiumSwap{
+				owner:  owner.PublicKey(),
+				signer: NewPrivateKeySigner(owner),
+			}
+			payer := owner.PublicKey()
+			if tt.feePayer {
+				feePayer := solana.NewWallet().PrivateKey
+				s.feePayer = NewPrivateKeySigner(feePayer)
+				payer = feePayer.PublicKey()
+			}
+
+			pool := testPool(baseMint, quoteMint)
+			instrs, _, err := s.Instructions(context.Background(), pool, 1000, 900, solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), false, tt.missing)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			created := []solana.PublicKey{}
+			swapped := false
+			for _, inst := range instrs {
+				switch {
+				case inst.ProgramID().Equals(RaydiumLiquidityPoolV4ProgramID):
+					swapped = true
+				case inst.ProgramID().Equals(solana.SPLAssociatedTokenAccountProgramID):
+					if swapped {
+						t.Fatalf("token account created after the swap")
+					}
+					accounts := inst.Accounts()
+					if !accounts[0].PublicKey.Equals(payer) || !accounts[2].PublicKey.Equals(owner.PublicKey()) {
+						t.Errorf("payer %v owner %v, want %v and %v", accounts[0].PublicKey, accounts[2].PublicKey, payer, owner.PublicKey())
+					}
+					created = append(created, accounts[3].PublicKey)
+				}
+			}
+
+			if len(created) != len(tt.want) {
+				t.Fatalf("created accounts for %v, want %v", created, tt.want)
+			}
+			for i := range created {
+				if !created[i].Equals(tt.want[i]) {
+					t.Fatalf("created accounts for %v, want %v", created, tt.want)
+				}
+			}
+		})
+	}
+}
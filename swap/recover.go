@@ -0,0 +1,197 @@
+package swap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"main/models"
+)
+
+// StaleQuoteAge after which a quoted trade is failed by RecoverTrades, its transaction
+// was never sent since sent trades are recorded before the broadcast
+const StaleQuoteAge = time.Minute
+
+// ErrNotSent quoted trade interrupted before its transaction was sent
+var ErrNotSent = errors.New("interrupted before the transaction was sent")
+
+// RecoverTrades resolves trades of owner left pending by a crashed or interrupted process to
+// confirmed, failed or expired, waiting for blockhashes to expire. It returns the trades
+// still pending when ctx is done, nonce trades stay pending until their nonce is advanced.
+func RecoverTrades(ctx context.Context, clientRPC *rpc.Client, owner solana.PublicKey, interval time.Duration) ([]models.Trade, error) {
+	if interval <= 0 {
+		interval = DefaultRebroadcastInterval
+	}
+
+	pending, err := models.GetPendingTrades(owner.String())
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+	log.Printf("recover: %v pending trades", len(pending))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		unresolved := []models.Trade{}
+		for i := range pending {
+			trade := &pending[i]
+			resolved, err := resolveTrade(ctx, clientRPC, trade)
+			if err != nil {
+				log.Printf("recover trade %v: %v", trade.ID, err)
+			}
+			if !resolved {
+				unresolved = append(unresolved, *trade)
+				continue
+			}
+			log.Printf("recover trade %v: %v %v", trade.ID, trade.Status, trade.Signature)
+			if err := trade.Save(); err != nil {
+				return unresolved, fmt.Errorf("record trade %v: %w", trade.ID, err)
+			}
+		}
+
+		pending = unresolved
+		if len(pending) == 0 {
+			return nil, nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return pending, ctx.Err()
+		}
+	}
+}
+
+// resolveTrade sets the final state of trade when it is known
+func resolveTrade(ctx context.Context, clientRPC *rpc.Client, trade *models.Trade) (bool, error) {
+	if trade.Status == models.TradeQuoted {
+		if time.Since(trade.CreatedAt) < StaleQuoteAge {
+			// May belong to a running process about to send it
+			return false, nil
+		}
+		trade.Status = models.TradeFailed
+		trade.Error = ErrNotSent.Error()
+		return true, nil
+	}
+
+	sig, err := solana.SignatureFromBase58(trade.Signature)
+	if err != nil {
+		return false, err
+	}
+	blockhash, err := solana.HashFromBase58(trade.Blockhash)
+	if err != nil {
+		return false, err
+	}
+	nonceAccount := solana.PublicKey{}
+	if trade.NonceAccount != "" {
+		nonceAccount, err = solana.PublicKeyFromBase58(trade.NonceAccount)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	// Expiry is checked first, a status missing after expiry means the transaction never landed
+	expired, err := blockhashExpired(ctx, clientRPC, blockhash, trade.LastValidBlockHeight, nonceAccount)
+	if err != nil {
+		return false, err
+	}
+
+	status, err := getSignatureStatus(ctx, clientRPC, sig)
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case commitmentReached(status, rpc.CommitmentConfirmed):
+		landTrade(ctx, clientRPC, trade, sig, status)
+		return true, nil
+	case expired && status == nil:
+		trade.Status = models.TradeExpired
+		if nonceAccount.IsZero() {
+			trade.Error = ErrBlockhashExpired.Error()
+		} else {
+			trade.Error = ErrNonceAdvanced.Error()
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// landTrade records the outcome and fill of a trade whose transaction landed
+func landTrade(ctx context.Context, clientRPC *rpc.Client, trade *models.Trade, sig solana.Signature, status *rpc.SignatureStatusesResult) {
+	now := time.Now()
+	trade.Slot = status.Slot
+	trade.LandedAt = &now
+	if status.Err != nil {
+		// The transaction resolves custom error codes to their program
+		tx, err := getTransaction(ctx, clientRPC, sig)
+		if err != nil {
+			log.Printf("get transaction %v: %v", sig, err)
+		}
+		trade.Status = models.TradeFailed
+		trade.Error = fmt.Errorf("%w: %w", ErrTransactionFailed, DecodeTransactionError(tx, status.Err)).Error()
+		return
+	}
+	trade.Status = models.TradeConfirmed
+
+	// Submitted transactions are recorded without their pool and mints
+	if trade.InMint == "" || trade.OutMint == "" {
+		return
+	}
+	owner, err := solana.PublicKeyFromBase58(trade.Owner)
+	if err != nil {
+		return
+	}
+	result := &SwapResult{
+		Confirmation: Confirmation{Signature: sig},
+		InAmount:     trade.Amount,
+		QuotedOut:    trade.QuotedOut,
+		MinOut:       trade.MinOut,
+	}
+	err = FetchSwapFill(
+		ctx,
+		clientRPC,
+		result,
+		owner,
+		solana.MustPublicKeyFromBase58(trade.InMint),
+		solana.MustPublicKeyFromBase58(trade.OutMint),
+	)
+	if err != nil {
+		log.Printf("fetch swap fill %v: %v", sig, err)
+		return
+	}
+
+	trade.ActualIn = result.ActualIn
+	trade.ActualOut = result.ActualOut
+	trade.Fee = result.Fee
+	trade.Filled = result.Filled
+	if result.Filled {
+		trade.Slippage = result.Slippage()
+	}
+}
+
+// getTransaction fetches and decodes a landed transaction
+func getTransaction(ctx context.Context, clientRPC *rpc.Client, sig solana.Signature) (*solana.Transaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	maxVersion := uint64(0)
+	res, err := clientRPC.GetTransaction(ctx, sig, &rpc.GetTransactionOpts{
+		Encoding:                       solana.EncodingBase64,
+		Commitment:                     rpc.CommitmentConfirmed,
+		MaxSupportedTransactionVersion: &maxVersion,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.Transaction.GetTransaction()
+}
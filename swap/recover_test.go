@@ -0,0 +1,107 @@
+package swap
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"main/models"
+)
+
+// nonceAccountData rpc account of an initialized nonce account of authority storing nonce
+func nonceAccountData(nonce solana.Hash, authority solana.PublicKey) map[string]interface{} {
+	data := make([]byte, NonceAccountSize)
+	binary.LittleEndian.PutUint32(data[4:], 1)
+	copy(data[8:], authority[:])
+	copy(data[40:], nonce[:])
+	return map[string]interface{}{
+		"data":       []string{base64.StdEncoding.EncodeToString(data), "base64"},
+		"executable": false,
+		"lamports":   1447680,
+		"owner":      solana.SystemProgramID.String(),
+		"rentEpoch":  0,
+	}
+}
+
+func TestResolveTrade(t *testing.T) {
+	blockhash := solana.Hash{1}
+	sent := func(nonce bool) models.Trade {
+		trade := models.Trade{
+			Status:               models.TradeSent,
+			Signature:            solana.Signature{1}.String(),
+			Blockhash:            blockhash.String(),
+			LastValidBlockHeight: 100,
+		}
+		if nonce {
+			trade.NonceAccount = solana.NewWallet().PublicKey().String()
+		}
+		return trade
+	}
+	quoted := func(age time.Duration) models.Trade {
+		trade := models.Trade{Status: models.TradeQuoted}
+		trade.CreatedAt = time.Now().Add(-age)
+		return trade
+	}
+	landed := func(txErr interface{}) []interface{} {
+		return []interface{}{map[string]interface{}{
+			"slot":               7,
+			"confirmations":      nil,
+			"err":                txErr,
+			"confirmationStatus": "confirmed",
+		}}
+	}
+
+	tests := []struct {
+		name        string
+		trade       models.Trade
+		blockHeight uint64
+		nonce       interface{}
+		status      []interface{}
+		resolved    bool
+		wantStatus  string
+		wantErr     string
+	}{
+		{name: "recent quote", trade: quoted(0), wantStatus: models.TradeQuoted},
+		{name: "stale quote", trade: quoted(2 * StaleQuoteAge), resolved: true, wantStatus: models.TradeFailed, wantErr: ErrNotSent.Error()},
+		{name: "sent in flight", trade: sent(false), blockHeight: 50, status: []interface{}{nil}, wantStatus: models.TradeSent},
+		{name: "blockhash expired", trade: sent(false), blockHeight: 200, status: []interface{}{nil}, resolved: true, wantStatus: models.TradeExpired, wantErr: ErrBlockhashExpired.Error()},
+		{name: "confirmed", trade: sent(false), blockHeight: 50, status: landed(nil), resolved: true, wantStatus: models.TradeConfirmed},
+		{name: "landed with error", trade: sent(false), blockHeight: 200, status: landed("AccountInUse"), resolved: true, wantStatus: models.TradeFailed, wantErr: ErrTransactionFailed.Error()},
+		{name: "nonce not advanced", trade: sent(true), nonce: nonceAccountData(blockhash, solana.PublicKey{}), status: []interface{}{nil}, wantStatus: models.TradeSent},
+		{name: "nonce advanced", trade: sent(true), nonce: nonceAccountData(solana.Hash{2}, solana.PublicKey{}), status: []interface{}{nil}, resolved: true, wantStatus: models.TradeExpired, wantErr: ErrNonceAdvanced.Error()},
+		{name: "nonce account closed", trade: sent(true), status: []interface{}{nil}, resolved: true, wantStatus: models.TradeExpired, wantErr: ErrNonceAdvanced.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slot := map[string]interface{}{"slot": 1}
+			server := rpcServer(t, map[string]interface{}{
+				"getBlockHeight":       tt.blockHeight,
+				"getAccountInfo":       map[string]interface{}{"context": slot, "value": tt.nonce},
+				"getSignatureStatuses": map[string]interface{}{"context": slot, "value": tt.status},
+				"getTransaction":       nil,
+			})
+			defer server.Close()
+
+			trade := tt.trade
+			resolved, err := resolveTrade(context.Background(), rpc.New(server.URL), &trade)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resolved != tt.resolved {
+				t.Fatalf("resolved = %v, want %v", resolved, tt.resolved)
+			}
+			if trade.Status != tt.wantStatus {
+				t.Fatalf("status = %v, want %v", trade.Status, tt.wantStatus)
+			}
+			if !strings.Contains(trade.Error, tt.wantErr) {
+				t.Fatalf("error = %q, want %q", trade.Error, tt.wantErr)
+			}
+		})
+	}
+}
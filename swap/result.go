@@ -0,0 +1,152 @@
+package swap
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	rayLogPrefix = "Program log: ray_log: "
+	// rayLogSwapBaseIn log type written by the swap base in instruction
+	rayLogSwapBaseIn = 3
+)
+
+// ErrNoFill transaction has no swap fill for the owner
+var ErrNoFill = errors.New("swap fill not found in transaction")
+
+// SwapResult actual fill of a confirmed swap compared against its quote
+type SwapResult struct {
+	Confirmation
+	// InAmount requested input in base units
+	InAmount uint64
+	// QuotedOut estimated output at the time of the swap
+	QuotedOut uint64
+	// MinOut minimum output accepted by the swap instruction
+	MinOut uint64
+	// ActualIn input taken by the pool
+	ActualIn uint64
+	// ActualOut output received
+	ActualOut uint64
+	// Fee transaction fee in lamports
+	Fee uint64
+	// Filled is set once ActualIn and ActualOut were parsed from the transaction
+	Filled bool
+}
+
+// Slippage percent by which ActualOut is below QuotedOut
+func (r *SwapResult) Slippage() float64 {
+	if r.QuotedOut == 0 {
+		return 0
+	}
+	return (float64(r.QuotedOut) - float64(r.ActualOut)) / float64(r.QuotedOut) * 100.0
+}
+
+// RaySwapBaseInLog x
+type RaySwapBaseInLog struct {
+	LogType    uint8
+	AmountIn   uint64
+	MinimumOut uint64
+	Direction  uint64
+	UserSource uint64
+	PoolCoin   uint64
+	PoolPc     uint64
+	OutAmount  uint64
+}
+
+// ParseRayLog decodes the swap base in entry of Raydium program logs
+func ParseRayLog(logs []string) (*RaySwapBaseInLog, error) {
+	for _, l := range logs {
+		if !strings.HasPrefix(l, rayLogPrefix) {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(l, rayLogPrefix))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) == 0 || data[0] != rayLogSwapBaseIn {
+			continue
+		}
+		var entry RaySwapBaseInLog
+		if err := bin.NewBinDecoder(data).Decode(&entry); err != nil {
+			return nil, fmt.Errorf("decoding ray_log: %w", err)
+		}
+		return &entry, nil
+	}
+	return nil, ErrNoFill
+}
+
+// FetchSwapFill fills result with the amounts, fee and slot of its confirmed transaction
+func FetchSwapFill(
+	ctx context.Context,
+	clientRPC *rpc.Client,
+	result *SwapResult,
+	owner solana.PublicKey,
+	inMint solana.PublicKey,
+	outMint solana.PublicKey,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	maxVersion := uint64(0)
+	tx, err := clientRPC.GetTransaction(ctx, result.Signature, &rpc.GetTransactionOpts{
+		Encoding:                       solana.EncodingBase64,
+		Commitment:                     rpc.CommitmentConfirmed,
+		MaxSupportedTransactionVersion: &maxVersion,
+	})
+	if err != nil {
+		return err
+	}
+	if tx.Meta == nil {
+		return ErrNoFill
+	}
+
+	result.Slot = tx.Slot
+	result.Fee = tx.Meta.Fee
+
+	if entry, err := ParseRayLog(tx.Meta.LogMessages); err == nil {
+		result.ActualIn = entry.AmountIn
+		result.ActualOut = entry.OutAmount
+		result.Filled = true
+		return nil
+	}
+
+	// Fall back to owner token balance changes, wrapped SOL legs go through
+	// a temporary account and are only visible in the ray_log
+	spent := tokenBalanceChange(tx.Meta, owner, inMint)
+	received := tokenBalanceChange(tx.Meta, owner, outMint)
+	if spent.Sign() >= 0 || received.Sign() <= 0 {
+		return ErrNoFill
+	}
+
+	result.ActualIn = new(big.Int).Neg(spent).Uint64()
+	result.ActualOut = received.Uint64()
+	result.Filled = true
+	return nil
+}
+
+// tokenBalanceChange post minus pre balance of mint held by owner
+func tokenBalanceChange(meta *rpc.TransactionMeta, owner solana.PublicKey, mint solana.PublicKey) *big.Int {
+	sum := func(balances []rpc.TokenBalance) *big.Int {
+		total := new(big.Int)
+		for _, b := range balances {
+			if b.Owner == nil || !b.Owner.Equals(owner) || !b.Mint.Equals(mint) || b.UiTokenAmount == nil {
+				continue
+			}
+			amount, ok := new(big.Int).SetString(b.UiTokenAmount.Amount, 10)
+			if ok {
+				total.Add(total, amount)
+			}
+		}
+		return total
+	}
+	return new(big.Int).Sub(sum(meta.PostTokenBalances), sum(meta.PreTokenBalances))
+}
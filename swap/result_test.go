@@ -0,0 +1,81 @@
+package swap
+
+import (
+	"errors"
+	"testing"
+)
+
+// swapBaseInLogs program logs of a SOL to token swap through Raydium AMM v4
+var swapBaseInLogs = []string{
+	"Program ComputeBudget111111111111111111111111111111 invoke [1]",
+	"Program ComputeBudget111111111111111111111111111111 success",
+	"Program 675kPX9MHTjS2zt1qfr1NYHuzeLXfQM9H24wFSUt1Mp8 invoke [1]",
+	"Program log: ray_log: AwDKmjsAAAAAuNfYAAAAAAACAAAAAAAAAABcmVoAAAAAg6nRRq0BAAC8AspCPQAAADgxYQgAAAAA",
+	"Program TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA invoke [2]",
+	"Program TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA success",
+	"Program 675kPX9MHTjS2zt1qfr1NYHuzeLXfQM9H24wFSUt1Mp8 success",
+}
+
+func TestParseRayLog(t *testing.T) {
+	tests := []struct {
+		name    string
+		logs    []string
+		want    *RaySwapBaseInLog
+		wantErr error
+	}{
+		{
+			name: "swap base in",
+			logs: swapBaseInLogs,
+			want: &RaySwapBaseInLog{
+				LogType:    rayLogSwapBaseIn,
+				AmountIn:   1000000000,
+				MinimumOut: 14211000,
+				Direction:  2,
+				UserSource: 1520000000,
+				PoolCoin:   1843729115523,
+				PoolPc:     263113540284,
+				OutAmount:  140587320,
+			},
+		},
+		{
+			name:    "no ray_log",
+			logs:    swapBaseInLogs[:3],
+			wantErr: ErrNoFill,
+		},
+		{
+			// Deposit entries have another log type and are skipped
+			name:    "other log type",
+			logs:    []string{"Program log: ray_log: AQAAAAAAAAAA"},
+			wantErr: ErrNoFill,
+		},
+		{
+			name: "truncated",
+			logs: []string{"Program log: ray_log: AwDKmjsAAAAA"},
+		},
+		{
+			name: "invalid base64",
+			logs: []string{"Program log: ray_log: !!"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRayLog(tt.logs)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("ParseRayLog = %+v, want error", got)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != *tt.want {
+				t.Errorf("ParseRayLog = %+v, want %+v", *got, *tt.want)
+			}
+		})
+	}
+}
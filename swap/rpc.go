@@ -2,15 +2,56 @@ package swap
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	bin "github.com/gagliardetto/binary"
 	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
 	"github.com/gagliardetto/solana-go/programs/token"
 	"github.com/gagliardetto/solana-go/rpc"
 	"log"
+	"strings"
 	"time"
 )
 
+// MaxComputeUnitLimit per transaction
+const MaxComputeUnitLimit = 1400000
+
+// DefaultRebroadcastInterval between sends of an unconfirmed transaction
+const DefaultRebroadcastInterval = 2 * time.Second
+
+var (
+	// ErrSimulationFailed x
+	ErrSimulationFailed = errors.New("transaction simulation failed")
+	// ErrBlockhashExpired transaction was not confirmed before its blockhash expired, safe to retry
+	ErrBlockhashExpired = errors.New("blockhash expired before confirmation, transaction did not land")
+	// ErrMissingSignatures x
+	ErrMissingSignatures = errors.New("transaction is missing signatures")
+	// ErrTransactionFailed transaction landed with an error
+	ErrTransactionFailed = errors.New("transaction failed")
+)
+
+// ExecuteConfig x
+type ExecuteConfig struct {
+	// Simulate runs SimulateTransaction before sending and sizes the compute unit limit
+	Simulate bool
+	// ComputeUnitMargin percent added to simulated units consumed
+	ComputeUnitMargin float64
+	// RebroadcastInterval between sends until confirmation or blockhash expiry
+	RebroadcastInterval time.Duration
+	// LookupTables address lookup tables used to build a v0 transaction
+	LookupTables []solana.PublicKey
+	// WSEndpoint rpc websocket used for signatureSubscribe, polling only when empty
+	WSEndpoint string
+	// Commitment to wait for, confirmed by default
+	Commitment rpc.CommitmentType
+	// NonceAccount durable nonce used instead of a recent blockhash
+	NonceAccount solana.PublicKey
+	// OnSend is called with the signed transaction before its first broadcast,
+	// an error aborts the send
+	OnSend func(tx *solana.Transaction, lastValidBlockHeight uint64) error
+}
+
 // TokenAccountInfo x
 type TokenAccountInfo struct {
 	Mint    solana.PublicKey
@@ -19,6 +60,7 @@ type TokenAccountInfo struct {
 
 // GetTokenAccountsFromMints x
 func GetTokenAccountsFromMints(
+	ctx context.Context,
 	clientRPC rpc.Client,
 	owner solana.PublicKey,
 	mints ...solana.PublicKey,
@@ -48,7 +90,7 @@ func GetTokenAccountsFromMints(
 		})
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*20)
+	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
 	defer cancel()
 
 	res, err := clientRPC.GetMultipleAccounts(ctx, tokenAccounts...)
@@ -81,102 +123,434 @@ func GetTokenAccountsFromMints(
 }
 
 // BuildTransacion x
-func BuildTransacion(clientRPC *rpc.Client, signers []solana.PrivateKey, instrs ...solana.Instruction) (*solana.Transaction, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*20)
-	defer cancel()
+func BuildTransacion(
+	ctx context.Context,
+	clientRPC *rpc.Client,
+	signers []Signer,
+	tables map[solana.PublicKey]solana.PublicKeySlice,
+	instrs ...solana.Instruction,
+) (*solana.Transaction, uint64, error) {
+	recent, err := getLatestBlockhash(ctx, clientRPC)
+	if err != nil {
+		return nil, 0, err
+	}
 
-	recent, err := clientRPC.GetRecentBlockhash(ctx, rpc.CommitmentFinalized)
+	tx, err := newTransaction(signers[0].PublicKey(), recent.Blockhash, tables, instrs...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+
+	err = SignTransaction(ctx, tx, signers)
+	if err != nil {
+		return nil, 0, err
 	}
+	return tx, recent.LastValidBlockHeight, nil
+}
 
-	tx, err := solana.NewTransaction(
+// BuildUnsignedTransacion builds a transaction paid by payer and signed only by signers,
+// the remaining signatures are left empty for an external signer
+func BuildUnsignedTransacion(
+	ctx context.Context,
+	clientRPC *rpc.Client,
+	payer solana.PublicKey,
+	signers []Signer,
+	tables map[solana.PublicKey]solana.PublicKeySlice,
+	cfg ExecuteConfig,
+	instrs ...solana.Instruction,
+) (*solana.Transaction, uint64, error) {
+	var blockhash solana.Hash
+	var lastValidBlockHeight uint64
+
+	if !cfg.NonceAccount.IsZero() {
+		nonce, nonceInstrs, err := withAdvanceNonce(ctx, clientRPC, cfg.NonceAccount, instrs...)
+		if err != nil {
+			return nil, 0, err
+		}
+		blockhash = nonce
+		instrs = nonceInstrs
+	} else {
+		recent, err := getLatestBlockhash(ctx, clientRPC)
+		if err != nil {
+			return nil, 0, err
+		}
+		blockhash = recent.Blockhash
+		lastValidBlockHeight = recent.LastValidBlockHeight
+	}
+
+	tx, err := newTransaction(payer, blockhash, tables, instrs...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = PartialSign(ctx, tx, signers)
+	if err != nil {
+		return nil, 0, err
+	}
+	return tx, lastValidBlockHeight, nil
+}
+
+// MissingSigners returns the signers of tx without a signature yet
+func MissingSigners(tx *solana.Transaction) []solana.PublicKey {
+	missing := []solana.PublicKey{}
+	for i, key := range tx.Message.Signers() {
+		if i >= len(tx.Signatures) || tx.Signatures[i].IsZero() {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+func newTransaction(
+	payer solana.PublicKey,
+	blockhash solana.Hash,
+	tables map[solana.PublicKey]solana.PublicKeySlice,
+	instrs ...solana.Instruction,
+) (*solana.Transaction, error) {
+	opts := []solana.TransactionOption{
+		solana.TransactionPayer(payer),
+	}
+	if len(tables) > 0 {
+		opts = append(opts, solana.TransactionAddressTables(tables))
+	}
+
+	return solana.NewTransaction(
 		instrs,
-		recent.Value.Blockhash,
-		solana.TransactionPayer(signers[0].PublicKey()),
+		blockhash,
+		opts...,
 	)
+}
+
+func getLatestBlockhash(ctx context.Context, clientRPC *rpc.Client) (*rpc.LatestBlockhashResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	recent, err := clientRPC.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
 	if err != nil {
 		return nil, err
 	}
+	return recent.Value, nil
+}
 
-	_, err = tx.Sign(
-		func(key solana.PublicKey) *solana.PrivateKey {
-			for _, payer := range signers {
-				if payer.PublicKey().Equals(key) {
-					return &payer
-				}
-			}
-			return nil
-		},
-	)
+// buildTransaction builds with the nonce account of cfg when set, or the latest blockhash
+func buildTransaction(
+	ctx context.Context,
+	clientRPC *rpc.Client,
+	signers []Signer,
+	tables map[solana.PublicKey]solana.PublicKeySlice,
+	cfg ExecuteConfig,
+	instrs ...solana.Instruction,
+) (*solana.Transaction, uint64, error) {
+	if !cfg.NonceAccount.IsZero() {
+		tx, err := BuildNonceTransacion(ctx, clientRPC, signers, tables, cfg.NonceAccount, instrs...)
+		return tx, 0, err
+	}
+	return BuildTransacion(ctx, clientRPC, signers, tables, instrs...)
+}
+
+// SimulateInstructions x
+func SimulateInstructions(
+	ctx context.Context,
+	clientRPC *rpc.Client,
+	signers []Signer,
+	tables map[solana.PublicKey]solana.PublicKeySlice,
+	cfg ExecuteConfig,
+	instrs ...solana.Instruction,
+) (*rpc.SimulateTransactionResult, error) {
+	tx, _, err := buildTransaction(ctx, clientRPC, signers, tables, cfg, instrs...)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	res, err := clientRPC.SimulateTransactionWithOpts(ctx, tx, &rpc.SimulateTransactionOpts{
+		Commitment: rpc.CommitmentProcessed,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res.Value == nil {
+		return nil, fmt.Errorf("%w: empty result", ErrSimulationFailed)
+	}
+	if res.Value.Err != nil {
+		return res.Value, fmt.Errorf("%w: %w\n%s", ErrSimulationFailed, DecodeTransactionError(tx, res.Value.Err), strings.Join(res.Value.Logs, "\n"))
+	}
+
+	return res.Value, nil
+}
+
+// WithComputeUnitLimit replaces any compute unit limit in instrs with units
+func WithComputeUnitLimit(units uint32, instrs ...solana.Instruction) ([]solana.Instruction, error) {
+	limitInst, err := computebudget.NewSetComputeUnitLimitInstruction(units).ValidateAndBuild()
 	if err != nil {
 		return nil, err
 	}
-	return tx, nil
+
+	res := []solana.Instruction{limitInst}
+	for _, inst := range instrs {
+		if inst.ProgramID().Equals(solana.ComputeBudget) {
+			data, err := inst.Data()
+			if err != nil {
+				return nil, err
+			}
+			if len(data) > 0 && data[0] == computebudget.Instruction_SetComputeUnitLimit {
+				continue
+			}
+		}
+		res = append(res, inst)
+	}
+
+	return res, nil
 }
 
 // ExecuteInstructionsAndWait x
 func ExecuteInstructionsAndWait(
+	ctx context.Context,
 	clientRPC *rpc.Client,
-	signers []solana.PrivateKey,
+	signers []Signer,
+	cfg ExecuteConfig,
 	instrs ...solana.Instruction,
-) (*solana.Signature, error) {
+) (*Confirmation, error) {
 
-	tx, err := BuildTransacion(clientRPC, signers, instrs...)
+	tables, err := GetAddressTables(ctx, clientRPC, cfg.LookupTables...)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Simulate {
+		sim, err := SimulateInstructions(ctx, clientRPC, signers, tables, cfg, instrs...)
+		if err != nil {
+			return nil, err
+		}
+		if sim.UnitsConsumed != nil {
+			units := float64(*sim.UnitsConsumed) * (1 + cfg.ComputeUnitMargin/100.0)
+			if units > MaxComputeUnitLimit {
+				units = MaxComputeUnitLimit
+			}
+			log.Printf("simulated units: %v limit: %v", *sim.UnitsConsumed, uint32(units))
+			instrs, err = WithComputeUnitLimit(uint32(units), instrs...)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	tx, lastValidBlockHeight, err := buildTransaction(ctx, clientRPC, signers, tables, cfg, instrs...)
 	if err != nil {
 		return nil, err
 	}
 	log.Printf("builded")
+
+	return SendAndWait(ctx, clientRPC, tx, lastValidBlockHeight, cfg)
+}
+
+// SendAndWait rebroadcasts tx until it is confirmed or its blockhash expires
+func SendAndWait(
+	ctx context.Context,
+	clientRPC *rpc.Client,
+	tx *solana.Transaction,
+	lastValidBlockHeight uint64,
+	cfg ExecuteConfig,
+) (*Confirmation, error) {
+	maxRetries := uint(0)
 	opts := rpc.TransactionOpts{
 		SkipPreflight:       true,
 		PreflightCommitment: rpc.CommitmentFinalized,
+		MaxRetries:          &maxRetries,
+	}
+
+	interval := cfg.RebroadcastInterval
+	if interval <= 0 {
+		interval = DefaultRebroadcastInterval
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*20)
+	commitment := cfg.Commitment
+	if commitment == "" {
+		commitment = rpc.CommitmentConfirmed
+	}
+	// An unknown commitment would count as reached at processed
+	if _, err := ParseCommitment(string(commitment)); err != nil {
+		return nil, err
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, time.Second*20)
 	defer cancel()
 
+	if cfg.OnSend != nil {
+		if err := cfg.OnSend(tx, lastValidBlockHeight); err != nil {
+			return nil, err
+		}
+	}
+
+	start := time.Now()
 	xsig, err := clientRPC.SendTransactionWithOpts(
-		ctx,
+		sendCtx,
 		tx,
 		opts,
 	)
-	sig := &xsig
+	conf := &Confirmation{Signature: xsig}
 
 	if err != nil {
-		log.Printf("ExecuteInstructionsAndWait: %v %v", sig, err)
-		return sig, err
+		log.Printf("ExecuteInstructionsAndWait: %v %v", xsig, err)
+		return conf, err
 	}
 	log.Printf("sent")
 
-	var txErr interface{}
+	finish := func(slot uint64, txErr interface{}) (*Confirmation, error) {
+		conf.Slot = slot
+		conf.Duration = time.Since(start)
+		log.Printf("ExecuteInstructionsAndWait: slot %v in %v err %v", conf.Slot, conf.Duration, txErr)
+		if txErr != nil {
+			return conf, fmt.Errorf("%w: %w", ErrTransactionFailed, DecodeTransactionError(tx, txErr))
+		}
+		return conf, nil
+	}
 
-	for start := time.Now(); time.Since(start) < 120*time.Second; {
+	var notifications <-chan signatureNotification
+	if cfg.WSEndpoint != "" {
+		wsCtx, wsCancel := context.WithCancel(ctx)
+		defer wsCancel()
+		notifications = subscribeSignature(wsCtx, cfg.WSEndpoint, xsig, commitment)
+	}
 
-		ctx2, cancel2 := context.WithTimeout(context.Background(), time.Second*20)
-		defer cancel2()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
-		sigStatus, err := clientRPC.GetSignatureStatuses(ctx2, false, xsig)
-		if err == nil {
-			if sigStatus != nil && sigStatus.Value != nil && len(sigStatus.Value) > 0 && sigStatus.Value[0] != nil {
-				if sigStatus.Value[0].ConfirmationStatus != rpc.ConfirmationStatusProcessed {
-					txErr = sigStatus.Value[0].Err
-					log.Printf("ExecuteInstructionsAndWait: %v", txErr)
-					break
-				}
+	for {
+		select {
+		case n := <-notifications:
+			if n.err != nil {
+				log.Printf("signatureSubscribe: %v, falling back to polling", n.err)
+				notifications = nil
+				continue
 			}
-		} else {
-			txErr = err
+			return finish(n.slot, n.txErr)
+		case <-ticker.C:
+		case <-ctx.Done():
+			// The transaction may still land, the caller keeps the signature to check it
+			return conf, ctx.Err()
+		}
+
+		status, err := getSignatureStatus(ctx, clientRPC, xsig)
+		if err != nil {
+			log.Printf("ExecuteInstructionsAndWait: %v", err)
+		} else if commitmentReached(status, commitment) {
+			return finish(status.Slot, status.Err)
+		}
+
+		expired, err := transactionExpired(ctx, clientRPC, tx, lastValidBlockHeight, cfg)
+		if err != nil {
+			log.Printf("ExecuteInstructionsAndWait: %v", err)
+			continue
 		}
-		time.Sleep(5000 * time.Millisecond)
+
+		if expired {
+			// The transaction can no longer land, check once more for a late confirmation
+			status, err := getSignatureStatus(ctx, clientRPC, xsig)
+			if err == nil && status != nil {
+				return finish(status.Slot, status.Err)
+			}
+			if !cfg.NonceAccount.IsZero() {
+				return conf, ErrNonceAdvanced
+			}
+			return conf, ErrBlockhashExpired
+		}
+
+		ctx2, cancel2 := context.WithTimeout(ctx, time.Second*20)
+		_, err = clientRPC.SendTransactionWithOpts(ctx2, tx, opts)
+		cancel2()
+		if err != nil {
+			log.Printf("rebroadcast: %v", err)
+		}
+	}
+}
+
+func getSignatureStatus(ctx context.Context, clientRPC *rpc.Client, sig solana.Signature) (*rpc.SignatureStatusesResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	res, err := clientRPC.GetSignatureStatuses(ctx, true, sig)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil || len(res.Value) == 0 {
+		return nil, nil
+	}
+	return res.Value[0], nil
+}
+
+// SubmitTransaction sends an externally signed transaction and waits for its confirmation,
+// transactions advancing a nonce account are tracked by their nonce
+func SubmitTransaction(
+	ctx context.Context,
+	clientRPC *rpc.Client,
+	tx *solana.Transaction,
+	lastValidBlockHeight uint64,
+	cfg ExecuteConfig,
+) (*Confirmation, error) {
+	if missing := MissingSigners(tx); len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %v", ErrMissingSignatures, missing)
+	}
+	if err := tx.VerifySignatures(); err != nil {
+		return nil, err
 	}
 
-	log.Printf("sent loop end: %v", txErr)
+	if nonceAccount, ok := NonceAccountOf(tx); ok {
+		cfg.NonceAccount = nonceAccount
+	}
+
+	return SendAndWait(ctx, clientRPC, tx, lastValidBlockHeight, cfg)
+}
+
+// transactionExpired reports whether tx can no longer land
+func transactionExpired(
+	ctx context.Context,
+	clientRPC *rpc.Client,
+	tx *solana.Transaction,
+	lastValidBlockHeight uint64,
+	cfg ExecuteConfig,
+) (bool, error) {
+	return blockhashExpired(ctx, clientRPC, tx.Message.RecentBlockhash, lastValidBlockHeight, cfg.NonceAccount)
+}
+
+// blockhashExpired reports whether a transaction built with blockhash, or the nonce of
+// nonceAccount when it is set, can no longer land
+func blockhashExpired(
+	ctx context.Context,
+	clientRPC *rpc.Client,
+	blockhash solana.Hash,
+	lastValidBlockHeight uint64,
+	nonceAccount solana.PublicKey,
+) (bool, error) {
+	if !nonceAccount.IsZero() {
+		return nonceAdvanced(ctx, clientRPC, blockhash, nonceAccount)
+	}
+
+	// Externally built transactions may come without their last valid block height
+	if lastValidBlockHeight == 0 {
+		ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+		defer cancel()
+
+		res, err := clientRPC.IsBlockhashValid(ctx, blockhash, rpc.CommitmentProcessed)
+		if err != nil {
+			return false, err
+		}
+		return !res.Value, nil
+	}
 
-	if txErr != nil {
-		return sig, fmt.Errorf("confirmation err: %v", txErr)
+	height, err := getBlockHeight(ctx, clientRPC)
+	if err != nil {
+		return false, err
 	}
+	return height > lastValidBlockHeight, nil
+}
+
+func getBlockHeight(ctx context.Context, clientRPC *rpc.Client) (uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
 
-	return sig, nil
+	return clientRPC.GetBlockHeight(ctx, rpc.CommitmentConfirmed)
 }
 
 // GetTokenAccountsBalance c
@@ -0,0 +1,227 @@
+package swap
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+func TestSendAndWait(t *testing.T) {
+	owner := solana.NewWallet().PrivateKey
+	nonceAccount := solana.NewWallet().PublicKey()
+	blockhash := solana.Hash{1}
+
+	tx, err := solana.NewTransaction(
+		[]solana.Instruction{solana.NewInstruction(solana.MemoProgramID, solana.AccountMetaSlice{}, []byte("test"))},
+		blockhash,
+		solana.TransactionPayer(owner.PublicKey()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SignTransaction(context.Background(), tx, []Signer{NewPrivateKeySigner(owner)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Polls count getSignatureStatuses calls, -1 never reaches the state
+	tests := []struct {
+		name       string
+		commitment rpc.CommitmentType
+		nonce      bool
+		// noHeight sends without a last valid block height, as submitted transactions may be
+		noHeight bool
+		// landAfter polls without a status, finalizeAfter polls with a confirmed status
+		landAfter     int
+		finalizeAfter int
+		// expireAfter expiry checks that find the transaction still valid
+		expireAfter int
+		txErr       interface{}
+		wantErr     error
+		wantSends   int
+	}{
+		{name: "confirmed on first poll", landAfter: 0, finalizeAfter: -1, expireAfter: -1, wantSends: 1},
+		{name: "rebroadcast until confirmed", landAfter: 3, finalizeAfter: -1, expireAfter: -1, wantSends: 4},
+		{name: "landed with error", landAfter: 1, finalizeAfter: -1, expireAfter: -1, txErr: "InsufficientFundsForFee", wantErr: ErrTransactionFailed, wantSends: 2},
+		{name: "blockhash expired", landAfter: -1, finalizeAfter: -1, expireAfter: 2, wantErr: ErrBlockhashExpired, wantSends: 3},
+		{name: "blockhash invalid without height", noHeight: true, landAfter: -1, finalizeAfter: -1, expireAfter: 2, wantErr: ErrBlockhashExpired, wantSends: 3},
+		{name: "confirmed without height", noHeight: true, landAfter: 2, finalizeAfter: -1, expireAfter: -1, wantSends: 3},
+		{name: "confirmed after expiry", landAfter: 3, finalizeAfter: -1, expireAfter: 2, wantSends: 3},
+		{name: "nonce advanced", nonce: true, landAfter: -1, finalizeAfter: -1, expireAfter: 1, wantErr: ErrNonceAdvanced, wantSends: 2},
+		{name: "waits for finalized", commitment: rpc.CommitmentFinalized, landAfter: 0, finalizeAfter: 2, expireAfter: -1, wantSends: 3},
+		{name: "invalid commitment", commitment: "max", landAfter: 0, expireAfter: -1, wantErr: ErrInvalidCommitment},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			sends, polls, checks := 0, 0, 0
+			expired := func() bool {
+				checks++
+				return tt.expireAfter >= 0 && checks > tt.expireAfter
+			}
+
+			slot := map[string]interface{}{"slot": 1}
+			server := rpcServer(t, map[string]interface{}{
+				"sendTransaction": rpcMethod(func(params []json.RawMessage) (interface{}, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					sends++
+					return tx.Signatures[0].String(), nil
+				}),
+				"getSignatureStatuses": rpcMethod(func(params []json.RawMessage) (interface{}, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					polls++
+					var status interface{}
+					if tt.landAfter >= 0 && polls > tt.landAfter {
+						confirmation := "confirmed"
+						if tt.finalizeAfter >= 0 && polls > tt.landAfter+tt.finalizeAfter {
+							confirmation = "finalized"
+						}
+						status = map[string]interface{}{
+							"slot":               7,
+							"confirmations":      nil,
+							"err":                tt.txErr,
+							"confirmationStatus": confirmation,
+						}
+					}
+					return map[string]interface{}{"context": slot, "value": []interface{}{status}}, nil
+				}),
+				"getBlockHeight": rpcMethod(func(params []json.RawMessage) (interface{}, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					if expired() {
+						return 101, nil
+					}
+					return 50, nil
+				}),
+				"isBlockhashValid": rpcMethod(func(params []json.RawMessage) (interface{}, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					return map[string]interface{}{"context": slot, "value": !expired()}, nil
+				}),
+				"getAccountInfo": rpcMethod(func(params []json.RawMessage) (interface{}, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					nonce := blockhash
+					if expired() {
+						nonce = solana.Hash{2}
+					}
+					return map[string]interface{}{"context": slot, "value": nonceAccountData(nonce, owner.PublicKey())}, nil
+				}),
+			})
+			defer server.Close()
+
+			cfg := ExecuteConfig{Commitment: tt.commitment, RebroadcastInterval: time.Millisecond}
+			if tt.nonce {
+				cfg.NonceAccount = nonceAccount
+			}
+			lastValidBlockHeight := uint64(100)
+			if tt.noHeight {
+				lastValidBlockHeight = 0
+			}
+			conf, err := SendAndWait(context.Background(), rpc.New(server.URL), tx, lastValidBlockHeight, cfg)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if conf.Signature != tx.Signatures[0] || conf.Slot != 7 {
+				t.Fatalf("confirmation %v at slot %v", conf.Signature, conf.Slot)
+			}
+			if sends != tt.wantSends {
+				t.Fatalf("%v sends, want %v", sends, tt.wantSends)
+			}
+		})
+	}
+}
+
+func TestWithComputeUnitLimit(t *testing.T) {
+	memo := solana.NewInstruction(solana.MemoProgramID, solana.AccountMetaSlice{}, []byte("a"))
+	price := computebudget.NewSetComputeUnitPriceInstruction(1000).Build()
+	limit := computebudget.NewSetComputeUnitLimitInstruction(200000).Build()
+
+	tests := []struct {
+		name   string
+		instrs []solana.Instruction
+		// want instructions after the new limit
+		want []solana.Instruction
+	}{
+		{name: "no budget", instrs: []solana.Instruction{memo}, want: []solana.Instruction{memo}},
+		{name: "keeps price", instrs: []solana.Instruction{price, memo}, want: []solana.Instruction{price, memo}},
+		{name: "replaces limit", instrs: []solana.Instruction{limit, price, memo}, want: []solana.Instruction{price, memo}},
+		{name: "replaces every limit", instrs: []solana.Instruction{limit, memo, limit}, want: []solana.Instruction{memo}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := WithComputeUnitLimit(150000, tt.instrs...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want)+1 {
+				t.Fatalf("%v instructions, want %v", len(got), len(tt.want)+1)
+			}
+
+			data, err := got[0].Data()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got[0].ProgramID().Equals(solana.ComputeBudget) || data[0] != computebudget.Instruction_SetComputeUnitLimit ||
+				binary.LittleEndian.Uint32(data[1:]) != 150000 {
+				t.Fatalf("first instruction %v %x, want a limit of 150000", got[0].ProgramID(), data)
+			}
+			for i, inst := range tt.want {
+				if got[i+1] != inst {
+					t.Errorf("instruction %v changed", i+1)
+				}
+			}
+		})
+	}
+}
+
+func TestSendAndWaitInterrupted(t *testing.T) {
+	owner := solana.NewWallet().PrivateKey
+	tx, err := solana.NewTransaction(
+		[]solana.Instruction{solana.NewInstruction(solana.MemoProgramID, solana.AccountMetaSlice{}, []byte("test"))},
+		solana.Hash{1},
+		solana.TransactionPayer(owner.PublicKey()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SignTransaction(context.Background(), tx, []Signer{NewPrivateKeySigner(owner)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The transaction never lands nor expires
+	server := rpcServer(t, map[string]interface{}{
+		"sendTransaction": tx.Signatures[0].String(),
+		"getSignatureStatuses": map[string]interface{}{
+			"context": map[string]interface{}{"slot": 1},
+			"value":   []interface{}{nil},
+		},
+		"getBlockHeight": 50,
+	})
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	conf, err := SendAndWait(ctx, rpc.New(server.URL), tx, 100, ExecuteConfig{RebroadcastInterval: time.Millisecond})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	// The caller keeps the signature to check whether the transaction landed later
+	if conf == nil || conf.Signature != tx.Signatures[0] {
+		t.Fatalf("confirmation %+v, want signature %v", conf, tx.Signatures[0])
+	}
+}
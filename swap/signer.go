@@ -0,0 +1,84 @@
+package swap
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// Signer signs transaction messages for one public key, the key material may live elsewhere
+type Signer interface {
+	PublicKey() solana.PublicKey
+	SignMessage(ctx context.Context, message []byte) (solana.Signature, error)
+}
+
+// PrivateKeySigner signs with a key held in memory
+type PrivateKeySigner struct {
+	key solana.PrivateKey
+}
+
+// NewPrivateKeySigner x
+func NewPrivateKeySigner(key solana.PrivateKey) *PrivateKeySigner {
+	return &PrivateKeySigner{key: key}
+}
+
+// PublicKey x
+func (s *PrivateKeySigner) PublicKey() solana.PublicKey {
+	return s.key.PublicKey()
+}
+
+// SignMessage x
+func (s *PrivateKeySigner) SignMessage(ctx context.Context, message []byte) (solana.Signature, error) {
+	return s.key.Sign(message)
+}
+
+// SignTransaction signs tx with signers, every required signature must be produced
+func SignTransaction(ctx context.Context, tx *solana.Transaction, signers []Signer) error {
+	err := PartialSign(ctx, tx, signers)
+	if err != nil {
+		return err
+	}
+	if missing := MissingSigners(tx); len(missing) > 0 {
+		return fmt.Errorf("%w: %v", ErrMissingSignatures, missing)
+	}
+	return nil
+}
+
+// PartialSign signs tx with the keys found in signers, other signatures stay empty
+func PartialSign(ctx context.Context, tx *solana.Transaction, signers []Signer) error {
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	required := tx.Message.Signers()
+	if len(tx.Signatures) != len(required) {
+		tx.Signatures = make([]solana.Signature, len(required))
+	}
+
+	for i, key := range required {
+		signer := findSigner(signers, key)
+		if signer == nil {
+			continue
+		}
+		sig, err := signer.SignMessage(ctx, message)
+		if err != nil {
+			return fmt.Errorf("sign with %v: %w", key, err)
+		}
+		if !sig.Verify(key, message) {
+			return fmt.Errorf("sign with %v: invalid signature", key)
+		}
+		tx.Signatures[i] = sig
+	}
+	return nil
+}
+
+func findSigner(signers []Signer, key solana.PublicKey) Signer {
+	for _, s := range signers {
+		if s.PublicKey().Equals(key) {
+			return s
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package swap
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// wrongKeySigner claims a public key but signs with another key
+type wrongKeySigner struct {
+	*PrivateKeySigner
+	claimed solana.PublicKey
+}
+
+func (s wrongKeySigner) PublicKey() solana.PublicKey {
+	return s.claimed
+}
+
+func TestPartialSign(t *testing.T) {
+	payer := NewPrivateKeySigner(solana.NewWallet().PrivateKey)
+	owner := NewPrivateKeySigner(solana.NewWallet().PrivateKey)
+	other := NewPrivateKeySigner(solana.NewWallet().PrivateKey)
+
+	tests := []struct {
+		name string
+		// presigned sign before the partial sign, as the external signer of an exported swap
+		presigned   []Signer
+		signers     []Signer
+		wantMissing []solana.PublicKey
+		wantErr     bool
+	}{
+		{name: "all signers", signers: []Signer{owner, payer}},
+		{name: "fee payer only", signers: []Signer{payer}, wantMissing: []solana.PublicKey{owner.PublicKey()}},
+		{name: "owner only", signers: []Signer{owner}, wantMissing: []solana.PublicKey{payer.PublicKey()}},
+		{name: "no signers", wantMissing: []solana.PublicKey{payer.PublicKey(), owner.PublicKey()}},
+		{name: "unrelated signer ignored", signers: []Signer{other, payer}, wantMissing: []solana.PublicKey{owner.PublicKey()}},
+		{name: "keeps existing signatures", presigned: []Signer{owner}, signers: []Signer{payer}},
+		{name: "invalid signature", signers: []Signer{wrongKeySigner{other, owner.PublicKey()}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, err := solana.NewTransaction(
+				[]solana.Instruction{solana.NewInstruction(
+					solana.MemoProgramID,
+					solana.AccountMetaSlice{solana.Meta(owner.PublicKey()).SIGNER()},
+					[]byte("test"),
+				)},
+				solana.Hash{1},
+				solana.TransactionPayer(payer.PublicKey()),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := PartialSign(context.Background(), tx, tt.presigned); err != nil {
+				t.Fatal(err)
+			}
+
+			err = PartialSign(context.Background(), tx, tt.signers)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			missing := MissingSigners(tx)
+			if len(missing) != len(tt.wantMissing) {
+				t.Fatalf("missing %v, want %v", missing, tt.wantMissing)
+			}
+			for i := range missing {
+				if !missing[i].Equals(tt.wantMissing[i]) {
+					t.Fatalf("missing %v, want %v", missing, tt.wantMissing)
+				}
+			}
+			if len(missing) == 0 {
+				if err := tx.VerifySignatures(); err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package swap
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"time"
 
@@ -18,6 +19,12 @@ var (
 	ErrUpdateBalances = errors.New("failed to update wallet balances")
 	// ErrFromBalanceNotEnough x
 	ErrFromBalanceNotEnough = errors.New("from balance not enough for swap")
+	// ErrNoPrivateKey swapper was created with an owner public key only
+	ErrNoPrivateKey = errors.New("private key required, build the transaction for an external signer instead")
+	// ErrNoOwner neither a private key nor an owner public key was configured
+	ErrNoOwner = errors.New("private key or owner required")
+	// ErrFeePayerIsOwner fee payer must be a different key than the wallet it pays for
+	ErrFeePayerIsOwner = errors.New("fee payer is the wallet itself")
 )
 
 // TaskConfig x
@@ -28,33 +35,45 @@ type TaskConfig struct {
 
 // TokenSwapperConfig x
 type TokenSwapperConfig struct {
-	ClientRPC  *rpc.Client
-	PrivateKey string
-	Pool       *models.PoolConfig
-	Reverse    bool
+	ClientRPC *rpc.Client
+	// Signer signs the swaps, PrivateKey is wrapped in a PrivateKeySigner when it is nil
+	Signer     Signer
+	PrivateKey solana.PrivateKey
+	// Owner public key used without a signer, swaps can only be built for external signing
+	Owner solana.PublicKey
+	// FeePayer pays transaction fees and account rent instead of the owner when set
+	FeePayer    Signer
+	Pool        *models.PoolConfig
+	Reverse     bool
+	PriorityFee PriorityFeeConfig
+	Execute     ExecuteConfig
+	Wrap        WrapConfig
 }
 
 // TokenSwapper x
 type TokenSwapper struct {
 	clientRPC       *rpc.Client
-	account         solana.PrivateKey
+	owner           solana.PublicKey
+	signer          Signer
 	raydiumSwap     *RaydiumSwap
 	tokenAccounts   map[string]solana.PublicKey
 	swapTask        TaskConfig
 	pool            *models.PoolConfig
 	reverse         bool
 	missingAccounts map[string]solana.PublicKey
-	IsMissingFrom   bool
-	IsMissingTo     bool
+	// trade recorded for the swap in progress
+	trade         *models.Trade
+	IsMissingFrom bool
+	IsMissingTo   bool
 }
 
 // GetPublic x
 func (s *TokenSwapper) GetPublic() string {
-	return s.account.PublicKey().String()
+	return s.owner.String()
 }
 
 // Init x
-func (s *TokenSwapper) Init() error {
+func (s *TokenSwapper) Init(ctx context.Context) error {
 
 	mints := []solana.PublicKey{}
 
@@ -70,7 +89,7 @@ func (s *TokenSwapper) Init() error {
 		mints = append(mints, solana.MustPublicKeyFromBase58(s.pool.QuoteMint))
 	}
 
-	existingAccounts, missingAccounts, err := GetTokenAccountsFromMints(*s.clientRPC, s.account.PublicKey(), mints...)
+	existingAccounts, missingAccounts, err := GetTokenAccountsFromMints(ctx, *s.clientRPC, s.owner, mints...)
 	if err != nil {
 		return err
 	}
@@ -81,48 +100,194 @@ func (s *TokenSwapper) Init() error {
 	return nil
 }
 
+// MissingMints mints of the pool without a token account of the owner
+func (s *TokenSwapper) MissingMints() []solana.PublicKey {
+	mints := []solana.PublicKey{}
+	for mint := range s.missingAccounts {
+		mints = append(mints, solana.MustPublicKeyFromBase58(mint))
+	}
+	return mints
+}
+
 // Do x
 func (s *TokenSwapper) Do(
+	ctx context.Context,
 	xamount float64,
 	slipage float64,
-) (*solana.Signature, error) {
+) (*SwapResult, error) {
+	return s.DoRaw(ctx, s.rawAmount(xamount), slipage)
+}
+
+// DoRaw swaps amount in base units of the input token, exact where a float amount may lose a unit
+func (s *TokenSwapper) DoRaw(
+	ctx context.Context,
+	amount uint64,
+	slipage float64,
+) (*SwapResult, error) {
+
+	estimated, _, mam, fromAddress, toAddress, err := s.EstimateRaw(ctx, amount, slipage)
+	if err != nil {
+		return nil, err
+	}
 
-	_, _, mam, fromAddress, toAddress, err := s.Estimate(xamount, slipage)
+	inMint, outMint, outDecimals := s.pool.BaseMint, s.pool.QuoteMint, s.pool.QuoteDecimals
+	if s.reverse {
+		inMint, outMint, outDecimals = s.pool.QuoteMint, s.pool.BaseMint, s.pool.BaseDecimals
+	}
+
+	result := &SwapResult{
+		InAmount:  s.swapTask.amount,
+		QuotedOut: FromFloat(estimated, outDecimals),
+		MinOut:    mam,
+	}
 
-	sig, err := s.raydiumSwap.Swap(
+	s.trade = &models.Trade{
+		Owner:     s.owner.String(),
+		PoolID:    s.pool.ID,
+		Reverse:   s.reverse,
+		InMint:    inMint,
+		OutMint:   outMint,
+		Amount:    result.InAmount,
+		QuotedOut: result.QuotedOut,
+		MinOut:    result.MinOut,
+		Slipage:   slipage,
+		Status:    models.TradeQuoted,
+	}
+	// Without a record a crash after sending could not be recovered
+	if err := s.trade.Create(); err != nil {
+		return result, fmt.Errorf("record trade: %w", err)
+	}
+	defer func() { s.trade = nil }()
+
+	conf, err := s.raydiumSwap.Swap(
+		ctx,
 		s.pool,
 		s.swapTask.amount,
 		mam,
 		fromAddress,
 		toAddress,
 		s.reverse,
-		s.IsMissingFrom,
-		s.IsMissingTo,
+		s.MissingMints(),
 	)
+	if conf != nil {
+		result.Confirmation = *conf
+	}
 
 	if err != nil {
-		return sig, err
+		s.recordFailure(err)
+		return result, err
+	}
+
+	now := time.Now()
+	s.trade.Status = models.TradeConfirmed
+	s.trade.Slot = result.Slot
+	s.trade.LandedAt = &now
+
+	err = FetchSwapFill(
+		ctx,
+		s.clientRPC,
+		result,
+		s.owner,
+		solana.MustPublicKeyFromBase58(inMint),
+		solana.MustPublicKeyFromBase58(outMint),
+	)
+	if err != nil {
+		// The swap landed, a missing fill only means less reporting
+		log.Printf("fetch swap fill %v: %v", result.Signature, err)
+	}
+
+	s.trade.ActualIn = result.ActualIn
+	s.trade.ActualOut = result.ActualOut
+	s.trade.Fee = result.Fee
+	s.trade.Filled = result.Filled
+	if result.Filled {
+		s.trade.Slippage = result.Slippage()
 	}
+	saveTrade(s.trade)
 
-	return sig, nil
+	return result, nil
+}
+
+// onSend persists the current trade as sent before its transaction is broadcast
+func (s *TokenSwapper) onSend(tx *solana.Transaction, lastValidBlockHeight uint64) error {
+	if s.trade == nil {
+		return nil
+	}
+	return markTradeSent(s.trade, tx, lastValidBlockHeight)
+}
+
+// recordFailure sets the final state of the current trade from a swap error
+func (s *TokenSwapper) recordFailure(err error) {
+	markTradeFailed(s.trade, err)
+	saveTrade(s.trade)
+}
+
+// Quote amounts a swap transaction was built with
+type Quote struct {
+	// Estimated output in tokens at the time of the build
+	Estimated float64
+	// MinimumOut in tokens, MinOut in base units as encoded in the swap instruction
+	MinimumOut float64
+	MinOut     uint64
+}
+
+// Build returns the swap transaction for an external signer with its last valid block height
+// and the quote encoded in it
+func (s *TokenSwapper) Build(
+	ctx context.Context,
+	xamount float64,
+	slipage float64,
+) (*solana.Transaction, uint64, *Quote, error) {
+
+	estimated, minimumOut, mam, fromAddress, toAddress, err := s.Estimate(ctx, xamount, slipage)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	tx, lastValidBlockHeight, err := s.raydiumSwap.Build(
+		ctx,
+		s.pool,
+		s.swapTask.amount,
+		mam,
+		fromAddress,
+		toAddress,
+		s.reverse,
+		s.MissingMints(),
+	)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	return tx, lastValidBlockHeight, &Quote{Estimated: estimated, MinimumOut: minimumOut, MinOut: mam}, nil
 }
 
 // Estimate x
 func (s *TokenSwapper) Estimate(
+	ctx context.Context,
 	xamount float64,
 	slipage float64,
+) (float64, float64, uint64, solana.PublicKey, solana.PublicKey, error) {
+	return s.EstimateRaw(ctx, s.rawAmount(xamount), slipage)
+}
+
+// rawAmount converts an amount of the input token to base units
+func (s *TokenSwapper) rawAmount(xamount float64) uint64 {
+	if s.reverse {
+		return FromFloat(xamount, s.pool.QuoteDecimals)
+	}
+	return FromFloat(xamount, s.pool.BaseDecimals)
+}
+
+// EstimateRaw estimates a swap of amount in base units of the input token
+func (s *TokenSwapper) EstimateRaw(
+	ctx context.Context,
+	amount uint64,
+	slipage float64,
 ) (float64, float64, uint64, solana.PublicKey, solana.PublicKey, error) {
 	var minimumOutAmount float64 = 0.0
-	var amount uint64 = 0
 	var mam uint64 = 0
 	var estimated = 0.0
 
-	if s.reverse == false {
-		amount = FromFloat(xamount, s.pool.BaseDecimals)
-	} else {
-		amount = FromFloat(xamount, s.pool.QuoteDecimals)
-	}
-
 	s.swapTask = TaskConfig{
 		amount:  amount,
 		slipage: slipage,
@@ -149,7 +314,7 @@ func (s *TokenSwapper) Estimate(
 	var fromAddress solana.PublicKey
 	missingFrom, ok := s.missingAccounts[fromToken]
 	if ok {
-		log.Printf("missingFrom ok: %#v", fromToken, missingFrom)
+		log.Printf("missingFrom ok: %v %v", fromToken, missingFrom)
 		fromAddress = missingFrom
 		s.IsMissingFrom = true
 	} else {
@@ -159,14 +324,14 @@ func (s *TokenSwapper) Estimate(
 	var toAddress solana.PublicKey
 	missingTo, ok := s.missingAccounts[toToken]
 	if ok {
-		log.Printf("missingTo ok: %#v", toToken, missingTo)
+		log.Printf("missingTo ok: %v %v", toToken, missingTo)
 		toAddress = missingTo
 		s.IsMissingTo = true
 	} else {
 		toAddress = s.tokenAccounts[toToken]
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
 	defer cancel()
 
 	res, err := s.clientRPC.GetMultipleAccounts(
@@ -234,19 +399,35 @@ func (s *TokenSwapper) Estimate(
 // NewTokenSwapper x
 func NewTokenSwapper(cfg TokenSwapperConfig) (*TokenSwapper, error) {
 
-	privateKey, err := solana.PrivateKeyFromBase58(cfg.PrivateKey)
-	if err != nil {
-		return nil, err
+	signer := cfg.Signer
+	if signer == nil && len(cfg.PrivateKey) != 0 {
+		signer = NewPrivateKeySigner(cfg.PrivateKey)
+	}
+	owner := cfg.Owner
+	if signer != nil {
+		owner = signer.PublicKey()
+	}
+	if owner.IsZero() {
+		return nil, ErrNoOwner
+	}
+	if cfg.FeePayer != nil && cfg.FeePayer.PublicKey().Equals(owner) {
+		return nil, ErrFeePayerIsOwner
 	}
 
 	raydiumSwap := RaydiumSwap{
-		clientRPC: cfg.ClientRPC,
-		account:   privateKey,
+		clientRPC:   cfg.ClientRPC,
+		owner:       owner,
+		signer:      signer,
+		feePayer:    cfg.FeePayer,
+		priorityFee: cfg.PriorityFee,
+		execute:     cfg.Execute,
+		wrap:        cfg.Wrap,
 	}
 
 	l := TokenSwapper{
 		clientRPC:     cfg.ClientRPC,
-		account:       privateKey,
+		owner:         owner,
+		signer:        signer,
 		raydiumSwap:   &raydiumSwap,
 		pool:          cfg.Pool,
 		reverse:       cfg.Reverse,
@@ -254,6 +435,17 @@ func NewTokenSwapper(cfg TokenSwapperConfig) (*TokenSwapper, error) {
 		IsMissingTo:   false,
 	}
 
+	onSend := cfg.Execute.OnSend
+	raydiumSwap.execute.OnSend = func(tx *solana.Transaction, lastValidBlockHeight uint64) error {
+		if err := l.onSend(tx, lastValidBlockHeight); err != nil {
+			return err
+		}
+		if onSend != nil {
+			return onSend(tx, lastValidBlockHeight)
+		}
+		return nil
+	}
+
 	return &l, nil
 }
 
@@ -0,0 +1,195 @@
+package swap
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"main/models"
+)
+
+// rpcMethod computes the result of a request from its params, an error is sent as a json rpc error
+type rpcMethod func(params []json.RawMessage) (interface{}, error)
+
+// rpcServer answers json rpc requests with the result registered for their method,
+// an rpcMethod is called for each request
+func rpcServer(t *testing.T, results map[string]interface{}) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     interface{}       `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+			return
+		}
+		result, ok := results[req.Method]
+		if !ok {
+			t.Errorf("unexpected request %v", req.Method)
+			return
+		}
+		res := map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+		}
+		if method, ok := result.(rpcMethod); ok {
+			var err error
+			result, err = method(req.Params)
+			if err != nil {
+				res["error"] = map[string]interface{}{"code": -32002, "message": err.Error()}
+				json.NewEncoder(w).Encode(res)
+				return
+			}
+		}
+		res["result"] = result
+		json.NewEncoder(w).Encode(res)
+	}))
+}
+
+// tokenAccount rpc account of a token account holding amount
+func tokenAccount(amount uint64) map[string]interface{} {
+	data := make([]byte, 165)
+	binary.LittleEndian.PutUint64(data[64:], amount)
+	return map[string]interface{}{
+		"data":       []string{base64.StdEncoding.EncodeToString(data), "base64"},
+		"executable": false,
+		"lamports":   2039280,
+		"owner":      solana.TokenProgramID.String(),
+		"rentEpoch":  0,
+	}
+}
+
+// vaultServer answers getMultipleAccounts with token accounts holding amounts
+func vaultServer(t *testing.T, amounts ...uint64) *httptest.Server {
+	t.Helper()
+
+	accounts := []interface{}{}
+	for _, amount := range amounts {
+		accounts = append(accounts, tokenAccount(amount))
+	}
+	return rpcServer(t, map[string]interface{}{
+		"getMultipleAccounts": map[string]interface{}{
+			"context": map[string]interface{}{"slot": 1},
+			"value":   accounts,
+		},
+	})
+}
+
+func TestAmountRoundTrip(t *testing.T) {
+	pool := &models.PoolConfig{
+		BaseMint:      solana.NewWallet().PublicKey().String(),
+		QuoteMint:     solana.SolMint.String(),
+		BaseDecimals:  2,
+		QuoteDecimals: 9,
+		BaseVault:     solana.NewWallet().PublicKey().String(),
+		QuoteVault:    solana.NewWallet().PublicKey().String(),
+	}
+
+	server := vaultServer(t, 1_000_000, 50_000_000_000)
+	defer server.Close()
+
+	swapper, err := NewTokenSwapper(TokenSwapperConfig{
+		ClientRPC: rpc.New(server.URL),
+		Owner:     solana.NewWallet().PublicKey(),
+		Pool:      pool,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 29 at 2 decimals comes back as 28 through ToFloat and FromFloat
+	tests := []struct {
+		amount uint64
+	}{
+		{1},
+		{29},
+		{57},
+		{999_999},
+	}
+
+	for _, tt := range tests {
+		if _, _, _, _, _, err := swapper.EstimateRaw(context.Background(), tt.amount, 50); err != nil {
+			t.Fatalf("estimate %v: %v", tt.amount, err)
+		}
+		if swapper.swapTask.amount != tt.amount {
+			t.Errorf("raw amount %v swapped as %v", tt.amount, swapper.swapTask.amount)
+		}
+	}
+}
+
+// sendServer lands sent transactions at slot 7, reject fails the send of a transaction
+// with its error and landed sets the error a transaction lands with
+func sendServer(t *testing.T, reject func(tx *solana.Transaction) error, landed func(tx *solana.Transaction) interface{}) *httptest.Server {
+	t.Helper()
+
+	var mu sync.Mutex
+	sent := map[solana.Signature]interface{}{}
+	slot := map[string]interface{}{"slot": 1}
+
+	return rpcServer(t, map[string]interface{}{
+		"getLatestBlockhash": map[string]interface{}{
+			"context": slot,
+			"value": map[string]interface{}{
+				"blockhash":            solana.Hash{1}.String(),
+				"lastValidBlockHeight": 100,
+			},
+		},
+		"getBlockHeight": 50,
+		"sendTransaction": rpcMethod(func(params []json.RawMessage) (interface{}, error) {
+			var encoded string
+			if err := json.Unmarshal(params[0], &encoded); err != nil {
+				return nil, err
+			}
+			tx := &solana.Transaction{}
+			if err := tx.UnmarshalBase64(encoded); err != nil {
+				return nil, err
+			}
+			if reject != nil {
+				if err := reject(tx); err != nil {
+					return nil, err
+				}
+			}
+			var txErr interface{}
+			if landed != nil {
+				txErr = landed(tx)
+			}
+			mu.Lock()
+			sent[tx.Signatures[0]] = txErr
+			mu.Unlock()
+			return tx.Signatures[0].String(), nil
+		}),
+		"getSignatureStatuses": rpcMethod(func(params []json.RawMessage) (interface{}, error) {
+			var sigs []solana.Signature
+			if err := json.Unmarshal(params[0], &sigs); err != nil {
+				return nil, err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			statuses := []interface{}{}
+			for _, sig := range sigs {
+				txErr, ok := sent[sig]
+				if !ok {
+					statuses = append(statuses, nil)
+					continue
+				}
+				statuses = append(statuses, map[string]interface{}{
+					"slot":               7,
+					"confirmations":      nil,
+					"err":                txErr,
+					"confirmationStatus": "confirmed",
+				})
+			}
+			return map[string]interface{}{"context": slot, "value": statuses}, nil
+		}),
+	})
+}
@@ -0,0 +1,99 @@
+package swap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"main/models"
+)
+
+// SubmitTrade records a signed swap transaction as a sent trade of owner before submitting it,
+// so an interrupted submit is resolved by RecoverTrades like a swap sent by TokenSwapper.Do
+func SubmitTrade(
+	ctx context.Context,
+	clientRPC *rpc.Client,
+	owner solana.PublicKey,
+	tx *solana.Transaction,
+	lastValidBlockHeight uint64,
+	cfg ExecuteConfig,
+) (*Confirmation, error) {
+	trade := &models.Trade{
+		Owner:  owner.String(),
+		Status: models.TradeQuoted,
+	}
+	if err := trade.Create(); err != nil {
+		return nil, fmt.Errorf("record trade: %w", err)
+	}
+
+	onSend := cfg.OnSend
+	cfg.OnSend = func(tx *solana.Transaction, lastValidBlockHeight uint64) error {
+		if err := markTradeSent(trade, tx, lastValidBlockHeight); err != nil {
+			return err
+		}
+		if onSend != nil {
+			return onSend(tx, lastValidBlockHeight)
+		}
+		return nil
+	}
+
+	conf, err := SubmitTransaction(ctx, clientRPC, tx, lastValidBlockHeight, cfg)
+	if err != nil {
+		markTradeFailed(trade, err)
+		saveTrade(trade)
+		return conf, err
+	}
+
+	now := time.Now()
+	trade.Status = models.TradeConfirmed
+	trade.Slot = conf.Slot
+	trade.LandedAt = &now
+	saveTrade(trade)
+
+	return conf, nil
+}
+
+// markTradeSent persists trade as sent with what RecoverTrades needs to resolve it
+func markTradeSent(trade *models.Trade, tx *solana.Transaction, lastValidBlockHeight uint64) error {
+	now := time.Now()
+	trade.Signature = tx.Signatures[0].String()
+	trade.Blockhash = tx.Message.RecentBlockhash.String()
+	trade.LastValidBlockHeight = lastValidBlockHeight
+	if nonceAccount, ok := NonceAccountOf(tx); ok {
+		trade.NonceAccount = nonceAccount.String()
+	}
+	trade.Status = models.TradeSent
+	trade.SentAt = &now
+	if err := trade.Save(); err != nil {
+		// Not broadcast, the trade fails as quoted
+		trade.Status = models.TradeQuoted
+		return fmt.Errorf("record trade %v: %w", trade.ID, err)
+	}
+	return nil
+}
+
+// markTradeFailed sets the final state of trade from a send error. A sent trade whose
+// transaction may still land stays sent, RecoverTrades resolves it.
+func markTradeFailed(trade *models.Trade, err error) {
+	switch {
+	case errors.Is(err, ErrBlockhashExpired), errors.Is(err, ErrNonceAdvanced):
+		trade.Status = models.TradeExpired
+	case errors.Is(err, ErrTransactionFailed), trade.Status != models.TradeSent:
+		trade.Status = models.TradeFailed
+	default:
+		// A failed send or wait may follow a broadcast that reached a leader
+		return
+	}
+	trade.Error = err.Error()
+}
+
+// saveTrade only logs errors, a landed swap does not fail because its record could not be written
+func saveTrade(trade *models.Trade) {
+	if err := trade.Save(); err != nil {
+		log.Printf("record trade %v: %v", trade.ID, err)
+	}
+}
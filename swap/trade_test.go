@@ -0,0 +1,40 @@
+package swap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"main/models"
+)
+
+func TestMarkTradeFailed(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     string
+		err        error
+		wantStatus string
+	}{
+		{name: "not sent", status: models.TradeQuoted, err: ErrSimulationFailed, wantStatus: models.TradeFailed},
+		{name: "interrupted before send", status: models.TradeQuoted, err: context.Canceled, wantStatus: models.TradeFailed},
+		{name: "landed with error", status: models.TradeSent, err: fmt.Errorf("%w: %w", ErrTransactionFailed, errors.New("custom")), wantStatus: models.TradeFailed},
+		{name: "blockhash expired", status: models.TradeSent, err: ErrBlockhashExpired, wantStatus: models.TradeExpired},
+		{name: "nonce advanced", status: models.TradeSent, err: ErrNonceAdvanced, wantStatus: models.TradeExpired},
+		{name: "send transport error", status: models.TradeSent, err: errors.New("connection reset"), wantStatus: models.TradeSent},
+		{name: "interrupted wait", status: models.TradeSent, err: context.DeadlineExceeded, wantStatus: models.TradeSent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trade := &models.Trade{Status: tt.status}
+			markTradeFailed(trade, tt.err)
+			if trade.Status != tt.wantStatus {
+				t.Fatalf("status = %v, want %v", trade.Status, tt.wantStatus)
+			}
+			if trade.Status == models.TradeSent && trade.Error != "" {
+				t.Fatalf("pending trade recorded error %q", trade.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package swap
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// ErrNoWrappedSOL wallet has no wrapped SOL account to unwrap
+var ErrNoWrappedSOL = errors.New("no wrapped SOL account")
+
+// WrapConfig selects how SOL is wrapped for swaps
+type WrapConfig struct {
+	// UseATA wraps through the wallet wSOL associated token account instead of a temp account
+	UseATA bool
+	// KeepWrapped leaves wSOL in the associated token account after the swap, implies UseATA
+	KeepWrapped bool
+}
+
+// WrappedSOLAccount wSOL associated token account of owner
+func WrappedSOLAccount(owner solana.PublicKey) (solana.PublicKey, error) {
+	ata, _, err := solana.FindAssociatedTokenAddress(owner, solana.SolMint)
+	return ata, err
+}
+
+// GetWrappedSOLBalance wSOL held by owner, zero when the account does not exist
+func GetWrappedSOLBalance(ctx context.Context, clientRPC *rpc.Client, owner solana.PublicKey) (uint64, error) {
+	ata, err := WrappedSOLAccount(owner)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	res, err := clientRPC.GetMultipleAccounts(ctx, ata)
+	if err != nil {
+		return 0, err
+	}
+	if len(res.Value) == 0 || res.Value[0] == nil {
+		return 0, nil
+	}
+
+	var account token.Account
+	err = bin.NewBinDecoder(res.Value[0].Data.GetBinary()).Decode(&account)
+	if err != nil {
+		return 0, err
+	}
+
+	return account.Amount, nil
+}
+
+// NewWrapSOLInstructions creates the wSOL associated token account if needed, paid by payer,
+// and tops it up from owner to lamports of wrapped SOL
+func NewWrapSOLInstructions(
+	ctx context.Context,
+	clientRPC *rpc.Client,
+	payer solana.PublicKey,
+	owner solana.PublicKey,
+	lamports uint64,
+) ([]solana.Instruction, solana.PublicKey, error) {
+	ata, err := WrappedSOLAccount(owner)
+	if err != nil {
+		return nil, ata, err
+	}
+
+	createInst, err := NewCreateIdempotentATAInstruction(payer, owner, solana.SolMint)
+	if err != nil {
+		return nil, ata, err
+	}
+	instrs := []solana.Instruction{createInst}
+
+	if lamports == 0 {
+		return instrs, ata, nil
+	}
+
+	balance, err := GetWrappedSOLBalance(ctx, clientRPC, owner)
+	if err != nil {
+		return nil, ata, err
+	}
+	if balance >= lamports {
+		return instrs, ata, nil
+	}
+
+	transferInst, err := system.NewTransferInstruction(
+		lamports-balance,
+		owner,
+		ata,
+	).ValidateAndBuild()
+	if err != nil {
+		return nil, ata, err
+	}
+
+	syncInst, err := token.NewSyncNativeInstruction(ata).ValidateAndBuild()
+	if err != nil {
+		return nil, ata, err
+	}
+
+	return append(instrs, transferInst, syncInst), ata, nil
+}
+
+// UnwrapSOL closes the wSOL associated token account, returning wrapped SOL and rent to the wallet
+func UnwrapSOL(
+	ctx context.Context,
+	clientRPC *rpc.Client,
+	signers []Signer,
+	cfg ExecuteConfig,
+) (*Confirmation, error) {
+	owner := signers[0].PublicKey()
+	ata, err := WrappedSOLAccount(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	getCtx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	res, err := clientRPC.GetMultipleAccounts(getCtx, ata)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Value) == 0 || res.Value[0] == nil {
+		return nil, ErrNoWrappedSOL
+	}
+
+	closeInst, err := token.NewCloseAccountInstruction(
+		ata,
+		owner,
+		owner,
+		[]solana.PublicKey{},
+	).ValidateAndBuild()
+	if err != nil {
+		return nil, err
+	}
+
+	return ExecuteInstructionsAndWait(ctx, clientRPC, signers, cfg, closeInst)
+}
+
+// newAccountRent rent exemption of a token account, zero when account already exists
+func newAccountRent(ctx context.Context, clientRPC *rpc.Client, account solana.PublicKey) (uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	res, err := clientRPC.GetMultipleAccounts(ctx, account)
+	if err != nil {
+		return 0, err
+	}
+	if len(res.Value) > 0 && res.Value[0] != nil {
+		return 0, nil
+	}
+
+	return clientRPC.GetMinimumBalanceForRentExemption(ctx, 165, rpc.CommitmentConfirmed)
+}
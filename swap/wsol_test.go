@@ -0,0 +1,82 @@
+package swap
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+func TestNewWrapSOLInstructions(t *testing.T) {
+	tests := []struct {
+		name     string
+		lamports uint64
+		// exists the wSOL account holding wrapped
+		exists       bool
+		wrapped      uint64
+		wantTransfer uint64
+	}{
+		{name: "receiving SOL", lamports: 0},
+		{name: "new account", lamports: 1000, wantTransfer: 1000},
+		{name: "top up", lamports: 1000, exists: true, wrapped: 400, wantTransfer: 600},
+		{name: "enough wrapped", lamports: 1000, exists: true, wrapped: 1500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var account interface{}
+			if tt.exists {
+				account = tokenAccount(tt.wrapped)
+			}
+			server := rpcServer(t, map[string]interface{}{
+				"getMultipleAccounts": map[string]interface{}{
+					"context": map[string]interface{}{"slot": 1},
+					"value":   []interface{}{account},
+				},
+			})
+			defer server.Close()
+
+			payer := solana.NewWallet().PublicKey()
+			owner := solana.NewWallet().PublicKey()
+			instrs, ata, err := NewWrapSOLInstructions(context.Background(), rpc.New(server.URL), payer, owner, tt.lamports)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want, _ := WrappedSOLAccount(owner); !ata.Equals(want) {
+				t.Fatalf("account %v, want %v", ata, want)
+			}
+
+			// The account is always created idempotently, paid by payer
+			if !instrs[0].ProgramID().Equals(solana.SPLAssociatedTokenAccountProgramID) || !instrs[0].Accounts()[0].PublicKey.Equals(payer) {
+				t.Fatalf("first instruction %v, want the account created by %v", instrs[0].ProgramID(), payer)
+			}
+			if tt.wantTransfer == 0 {
+				if len(instrs) != 1 {
+					t.Fatalf("%v instructions, want only the create", len(instrs))
+				}
+				return
+			}
+
+			if len(instrs) != 3 {
+				t.Fatalf("%v instructions, want create, transfer and sync", len(instrs))
+			}
+			data, _ := instrs[1].Data()
+			decoded, err := system.DecodeInstruction(instrs[1].Accounts(), data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			transfer, ok := decoded.Impl.(*system.Transfer)
+			if !ok || *transfer.Lamports != tt.wantTransfer ||
+				!transfer.GetFundingAccount().PublicKey.Equals(owner) || !transfer.GetRecipientAccount().PublicKey.Equals(ata) {
+				t.Fatalf("transfer %+v, want %v from the owner to %v", decoded.Impl, tt.wantTransfer, ata)
+			}
+			data, _ = instrs[2].Data()
+			if !instrs[2].ProgramID().Equals(solana.TokenProgramID) || data[0] != token.Instruction_SyncNative {
+				t.Fatalf("last instruction %v %x, want SyncNative", instrs[2].ProgramID(), data)
+			}
+		})
+	}
+}
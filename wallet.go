@@ -0,0 +1,50 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"main/swap"
+)
+
+// privateKeyEnv base58 private key used when no keypair file is given
+const privateKeyEnv = "SOLANA_PRIVATE_KEY"
+
+var wallet solana.PrivateKey
+
+// signer signs for the wallet, set from --remote-signer, --unlock or the wallet key
+var signer swap.Signer
+
+// loadWallet reads the Solana CLI keypair file, the base58 key from the environment
+// or the compiled in walletPK, in this order, an empty key when none is set
+func loadWallet(keypair string) (solana.PrivateKey, error) {
+	if keypair != "" {
+		if strings.HasPrefix(keypair, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, err
+			}
+			keypair = filepath.Join(home, keypair[2:])
+		}
+		return solana.PrivateKeyFromSolanaKeygenFile(keypair)
+	}
+
+	encoded := os.Getenv(privateKeyEnv)
+	if encoded == "" {
+		encoded = walletPK
+	}
+	if encoded == "" {
+		return nil, nil
+	}
+	return solana.PrivateKeyFromBase58(strings.TrimSpace(encoded))
+}
+
+func mustSigner() swap.Signer {
+	if signer == nil {
+		log.Fatalf("wallet key required, use --keypair, --unlock, --remote-signer or %v", privateKeyEnv)
+	}
+	return signer
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/gagliardetto/solana-go"
+	"main/keystore"
+	"main/models"
+	"main/remotesigner"
+	"main/swap"
+)
+
+type walletCmd struct {
+	Add    *walletAddCmd    `arg:"subcommand:add" help:"register a wallet"`
+	List   *walletListCmd   `arg:"subcommand:list" help:"list registered wallets"`
+	Remove *walletRemoveCmd `arg:"subcommand:remove" help:"forget a wallet, its key is left untouched"`
+	Show   *walletShowCmd   `arg:"subcommand:show" help:"show valued balances and recent transactions of a wallet"`
+}
+
+type walletAddCmd struct {
+	Label         string `arg:"positional,required" help:"wallet label"`
+	KeystoreLabel string `arg:"--keystore-label" help:"keystore wallet holding the key, the label by default"`
+	KeypairFile   string `arg:"--keypair-file" help:"Solana CLI keypair file holding the key"`
+	Remote        string `arg:"--remote" help:"remote signer url holding the key"`
+	Watch         string `arg:"--watch" help:"public key of a watch only wallet"`
+	Tags          string `arg:"--tags" help:"comma separated tags"`
+}
+
+type walletListCmd struct {
+	Tag string `arg:"--tag" help:"only wallets with this tag"`
+}
+
+type walletRemoveCmd struct {
+	Label string `arg:"positional,required" help:"wallet label"`
+}
+
+type walletShowCmd struct {
+	Label string `arg:"positional,required" help:"wallet label"`
+	Limit int    `arg:"--limit" default:"10" help:"recent trades to show"`
+}
+
+// applyWallet points the key flags at the key of the registered wallet label
+func applyWallet(args *cliArgs) {
+	w, err := models.GetWallet(args.Wallet)
+	if err != nil {
+		log.Fatalf("wallet %v: %v", args.Wallet, err)
+	}
+
+	kind, value, err := w.ParseKeyRef()
+	if err != nil {
+		log.Fatalf("wallet %v: %v", w.Label, err)
+	}
+	switch kind {
+	case models.KeyRefKeystore:
+		args.Unlock = value
+	case models.KeyRefKeypair:
+		args.Keypair = value
+	case models.KeyRefRemote:
+		args.Remote = value
+		args.Owner = w.PublicKey
+	case models.KeyRefWatch:
+		args.Owner = w.PublicKey
+	}
+}
+
+// checkWallet fails when the resolved signer does not match the registered wallet
+func checkWallet(label string) {
+	w, err := models.GetWallet(label)
+	if err != nil {
+		log.Fatalf("wallet %v: %v", label, err)
+	}
+	if signer != nil && signer.PublicKey().String() != w.PublicKey {
+		log.Fatalf("wallet %v: key %v does not match %v", label, signer.PublicKey(), w.PublicKey)
+	}
+}
+
+func doWallet(ctx context.Context, keyDir string, args walletCmd) {
+	switch {
+	case args.Add != nil:
+		addWallet(ctx, keyDir, *args.Add)
+	case args.List != nil:
+		wallets, err := models.GetWallets(args.List.Tag)
+		if err != nil {
+			log.Fatalf("list wallets: %v", err)
+		}
+		for _, w := range wallets {
+			fmt.Printf("%v\t%v\t%v\t%v\n", w.Label, w.PublicKey, w.KeyRef, w.Tags)
+		}
+	case args.Remove != nil:
+		if err := models.DeleteWallet(args.Remove.Label); err != nil {
+			log.Fatalf("remove wallet: %v", err)
+		}
+		log.Printf("removed %v", args.Remove.Label)
+	case args.Show != nil:
+		showWallet(ctx, *args.Show)
+	default:
+		log.Fatalf("wallet: expected add, list, remove or show")
+	}
+}
+
+func addWallet(ctx context.Context, keyDir string, args walletAddCmd) {
+	w := models.Wallet{
+		Label: args.Label,
+		Tags:  args.Tags,
+	}
+
+	switch {
+	case args.Watch != "":
+		w.PublicKey = mustPublicKey(args.Watch).String()
+		w.KeyRef = models.NewKeyRef(models.KeyRefWatch, "")
+	case args.KeypairFile != "":
+		key, err := solana.PrivateKeyFromSolanaKeygenFile(args.KeypairFile)
+		if err != nil {
+			log.Fatalf("keypair: %v", err)
+		}
+		w.PublicKey = key.PublicKey().String()
+		// Resolved now so the wallet works from any directory
+		path, err := filepath.Abs(args.KeypairFile)
+		if err != nil {
+			log.Fatalf("keypair: %v", err)
+		}
+		w.KeyRef = models.NewKeyRef(models.KeyRefKeypair, path)
+	case args.Remote != "":
+		client, err := remotesigner.New(ctx, args.Remote, os.Getenv(remoteTokenEnv), solana.PublicKey{})
+		if err != nil {
+			log.Fatalf("remote signer: %v", err)
+		}
+		w.PublicKey = client.PublicKey().String()
+		w.KeyRef = models.NewKeyRef(models.KeyRefRemote, args.Remote)
+	default:
+		label := args.KeystoreLabel
+		if label == "" {
+			label = args.Label
+		}
+		ks, err := keystore.Open(keyDir)
+		if err != nil {
+			log.Fatalf("open keystore: %v", err)
+		}
+		entry, err := ks.Get(label)
+		if err != nil {
+			log.Fatalf("keystore: %v", err)
+		}
+		w.PublicKey = entry.PublicKey.String()
+		w.KeyRef = models.NewKeyRef(models.KeyRefKeystore, label)
+	}
+
+	if err := w.Create(); err != nil {
+		log.Fatalf("add wallet: %v", err)
+	}
+	log.Printf("added %v: %v (%v)", w.Label, w.PublicKey, w.KeyRef)
+}
+
+func showWallet(ctx context.Context, args walletShowCmd) {
+	w, err := models.GetWallet(args.Label)
+	if err != nil {
+		log.Fatalf("wallet %v: %v", args.Label, err)
+	}
+	owner := mustPublicKey(w.PublicKey)
+
+	portfolio, err := swap.GetPortfolio(ctx, clientRPC, owner)
+	if err != nil {
+		log.Fatalf("balances: %v", err)
+	}
+	fmt.Printf("%v\t%v\t%v\n", w.Label, w.PublicKey, w.Tags)
+	printPortfolio(portfolio)
+
+	trades, err := models.GetTrades(models.TradeFilter{Owner: w.PublicKey, Limit: args.Limit})
+	if err != nil {
+		log.Fatalf("history: %v", err)
+	}
+	printTrades(trades)
+}
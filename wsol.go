@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"main/swap"
+)
+
+type unwrapCmd struct{}
+
+func doUnwrap(ctx context.Context, args unwrapCmd) {
+	walletSigner := mustSigner()
+
+	balance, err := swap.GetWrappedSOLBalance(ctx, clientRPC, walletSigner.PublicKey())
+	if err != nil {
+		log.Fatalf("get wSOL balance: %v", err)
+	}
+
+	conf, err := swap.UnwrapSOL(ctx, clientRPC, []swap.Signer{walletSigner}, swap.ExecuteConfig{WSEndpoint: wsURL, Commitment: commitment})
+	if errors.Is(err, swap.ErrNoWrappedSOL) {
+		log.Printf("nothing to unwrap")
+		return
+	}
+	if err != nil {
+		log.Fatalf("unwrap: %v", err)
+	}
+	log.Printf("unwrapped: %v sig: %v", swap.ToFloat(balance, 9), conf.Signature)
+}